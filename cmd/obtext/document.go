@@ -0,0 +1,162 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/JoshPattman/obtext"
+)
+
+// document is an open .obt file, along with its syntax tree if it is valid.
+type document struct {
+	uri  string
+	text []byte
+	// syn is the syntax tree, or nil if synErr is set.
+	syn    *obtext.ObjectSynNode
+	synErr error
+	// lineStarts is the offset of the first byte of each line.
+	lineStarts []int
+}
+
+func newDocument(uri, text string) *document {
+	d := newDocumentBytes(uri, []byte(text))
+	d.syn, d.synErr = obtext.ParseSynBytes(d.text)
+	return d
+}
+
+// newDocumentBytes returns a document with the given text, without parsing it.
+func newDocumentBytes(uri string, text []byte) *document {
+	d := &document{uri: uri, text: text, lineStarts: []int{0}}
+	for i, b := range d.text {
+		if b == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+	return d
+}
+
+// edit returns a new version of the document with the edit applied, only parsing the edited part again if possible.
+func (d *document) edit(e obtext.SynEdit) *document {
+	res := newDocumentBytes(d.uri, e.Apply(d.text))
+	res.syn, res.synErr = obtext.ReparseSynBytes(d.syn, res.text, e)
+	return res
+}
+
+// position converts a byte offset into an LSP position, which counts characters in UTF-16 code units.
+func (d *document) position(offset int) lspPosition {
+	offset = min(max(offset, 0), len(d.text))
+	line := sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset }) - 1
+	char := 0
+	for _, r := range string(d.text[d.lineStarts[line]:offset]) {
+		char += runeLen16(r)
+	}
+	return lspPosition{Line: line, Character: char}
+}
+
+// offset converts an LSP position into a byte offset.
+func (d *document) offset(pos lspPosition) int {
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+	offset := d.lineStarts[max(pos.Line, 0)]
+	for char := 0; char < pos.Character && offset < len(d.text) && d.text[offset] != '\n'; {
+		r, size := utf8.DecodeRune(d.text[offset:])
+		char += runeLen16(r)
+		offset += size
+	}
+	return offset
+}
+
+// runeLen16 returns the number of UTF-16 code units needed to encode the rune.
+func runeLen16(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// span returns the LSP range between two byte offsets.
+func (d *document) span(start, end int) lspRange {
+	return lspRange{Start: d.position(start), End: d.position(end)}
+}
+
+// nameEnd returns the offset just after the name of the object.
+func nameEnd(o *obtext.ObjectSynNode) int {
+	return o.Pos.Offset + 1 + len(o.Type)
+}
+
+// objectEnd returns the offset just after the last '}' of the object.
+func (d *document) objectEnd(o *obtext.ObjectSynNode) int {
+	if len(o.Args) == 0 {
+		return nameEnd(o)
+	}
+	return o.Args[len(o.Args)-1].End.Offset
+}
+
+// objectsAt returns every object that contains the offset, from the outermost to the innermost.
+func (d *document) objectsAt(offset int) []*obtext.ObjectSynNode {
+	var res []*obtext.ObjectSynNode
+	var visit func(o *obtext.ObjectSynNode)
+	visit = func(o *obtext.ObjectSynNode) {
+		if offset < o.Pos.Offset || offset > d.objectEnd(o) {
+			return
+		}
+		res = append(res, o)
+		for _, a := range o.Args {
+			for _, e := range a.Elements {
+				if child, ok := e.(*obtext.ObjectSynNode); ok {
+					visit(child)
+				}
+			}
+		}
+	}
+	if d.syn != nil {
+		visit(d.syn)
+	}
+	return res
+}
+
+// walk calls f for every object in the document, stopping early if f returns false.
+func (d *document) walk(f func(o *obtext.ObjectSynNode) bool) {
+	var visit func(o *obtext.ObjectSynNode) bool
+	visit = func(o *obtext.ObjectSynNode) bool {
+		if !f(o) {
+			return false
+		}
+		for _, a := range o.Args {
+			for _, e := range a.Elements {
+				if child, ok := e.(*obtext.ObjectSynNode); ok && !visit(child) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if d.syn != nil {
+		visit(d.syn)
+	}
+}
+
+// argText returns all of the text in the i-th arg of the object, including text inside nested objects.
+func argText(o *obtext.ObjectSynNode, i int) string {
+	if i >= len(o.Args) {
+		return ""
+	}
+	var sb strings.Builder
+	var visit func(a *obtext.ArgSynNode)
+	visit = func(a *obtext.ArgSynNode) {
+		for _, e := range a.Elements {
+			switch e := e.(type) {
+			case *obtext.TextSynNode:
+				sb.WriteString(e.Value)
+			case *obtext.ObjectSynNode:
+				for _, child := range e.Args {
+					visit(child)
+				}
+			}
+		}
+	}
+	visit(o.Args[i])
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/JoshPattman/obtext"
+)
+
+// diagnostics returns the syntax error of the document, or if it has none, all of its semantic errors.
+func (s *lspServer) diagnostics(d *document) []diagnostic {
+	res := []diagnostic{}
+	if d.synErr != nil {
+		var synErr *obtext.SynError
+		start, msg := 0, d.synErr.Error()
+		if errors.As(d.synErr, &synErr) {
+			start, msg = synErr.Pos.Offset, synErr.Msg
+		}
+		return append(res, diagnostic{Range: d.span(start, start+1), Severity: severityError, Source: "obtext", Message: msg})
+	}
+	_, err := s.reg.Parse(d.syn, obtext.WithAllErrors())
+	if err == nil {
+		return res
+	}
+	semErrs := obtext.SemErrors(err)
+	if len(semErrs) == 0 {
+		return append(res, diagnostic{Range: d.span(0, 0), Severity: severityError, Source: "obtext", Message: err.Error()})
+	}
+	for _, e := range semErrs {
+		msg := e.Err.Error()
+		if e.Hint != "" {
+			msg += "\nhint: " + e.Hint
+		}
+		res = append(res, diagnostic{Range: d.errorRange(e.Pos), Severity: severityError, Source: "obtext", Message: msg})
+	}
+	return res
+}
+
+// errorRange returns the range to highlight for an error at the given position, which is the name of an object or the rest of the line of some text.
+func (d *document) errorRange(pos obtext.Position) lspRange {
+	start := pos.Offset
+	end := start
+	if start < len(d.text) && d.text[start] == '@' {
+		end++
+		for end < len(d.text) && (isNameChar(d.text[end]) || d.text[end] == '.') {
+			end++
+		}
+	} else {
+		for end < len(d.text) && d.text[end] != '\n' && d.text[end] != '@' && d.text[end] != '}' {
+			end++
+		}
+	}
+	return d.span(start, max(end, start+1))
+}
+
+func isNameChar(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// completion returns every object name, including aliases.
+func (s *lspServer) completion() []completionItem {
+	res := make([]completionItem, 0, len(s.schemas))
+	for _, name := range s.reg.Names() {
+		schema := s.schemas[name]
+		res = append(res, completionItem{
+			Label:         name,
+			Kind:          completionItemKindKeyword,
+			Detail:        schema.Usage(),
+			Documentation: &markupContent{Kind: "markdown", Value: schemaMarkdown(schema)},
+		})
+	}
+	return res
+}
+
+// hover returns the documentation of the object whose name is at the offset.
+func (s *lspServer) hover(d *document, offset int) *hover {
+	objs := d.objectsAt(offset)
+	if len(objs) == 0 {
+		return nil
+	}
+	o := objs[len(objs)-1]
+	if offset > nameEnd(o) {
+		return nil
+	}
+	r := d.span(o.Pos.Offset, nameEnd(o))
+	schema, ok := s.schemas[o.Type]
+	if !ok {
+		return &hover{Contents: markupContent{Kind: "markdown", Value: fmt.Sprintf("`@%s` is not defined", o.Type)}, Range: r}
+	}
+	return &hover{Contents: markupContent{Kind: "markdown", Value: schemaMarkdown(schema)}, Range: r}
+}
+
+// schemaMarkdown formats the documentation of an object for hovers and completions.
+func schemaMarkdown(schema obtext.ObjectSchema) string {
+	out := "```\n" + schema.Usage() + "\n```\n"
+	if schema.Summary != "" {
+		out += "\n" + schema.Summary + "\n"
+	}
+	if len(schema.Args) > 0 {
+		out += "\n"
+		for _, a := range schema.Args {
+			out += fmt.Sprintf("- `%s` (%s)", a.Name, a.Kind)
+			if a.Description != "" {
+				out += ": " + a.Description
+			}
+			out += "\n"
+		}
+	}
+	if len(schema.Aliases) > 0 {
+		out += "\nAliases: @" + strings.Join(schema.Aliases, ", @") + "\n"
+	}
+	return out
+}
+
+// is returns true if the object is one of the given types, resolving aliases.
+func (s *lspServer) is(o *obtext.ObjectSynNode, types []string) bool {
+	if slices.Contains(types, o.Type) {
+		return true
+	}
+	target, ok := s.reg.Aliases()[o.Type]
+	return ok && slices.Contains(types, target)
+}
+
+// definition returns the location of the label that the ref at the offset refers to.
+func (s *lspServer) definition(d *document, offset int) []lspLocation {
+	res := []lspLocation{}
+	objs := d.objectsAt(offset)
+	var ref *obtext.ObjectSynNode
+	for _, o := range objs {
+		if s.is(o, s.refs) {
+			ref = o
+		}
+	}
+	if ref == nil {
+		return res
+	}
+	name := argText(ref, 0)
+	d.walk(func(o *obtext.ObjectSynNode) bool {
+		if s.is(o, s.labels) && argText(o, 0) == name {
+			res = append(res, lspLocation{URI: d.uri, Range: d.span(o.Pos.Offset, d.objectEnd(o))})
+		}
+		return true
+	})
+	return res
+}
+
+// documentSymbols returns the outline of the document, made of its sections.
+func (s *lspServer) documentSymbols(d *document) []documentSymbol {
+	var visit func(o *obtext.ObjectSynNode) []documentSymbol
+	visit = func(o *obtext.ObjectSynNode) []documentSymbol {
+		var children []documentSymbol
+		for _, a := range o.Args {
+			for _, e := range a.Elements {
+				if child, ok := e.(*obtext.ObjectSynNode); ok {
+					children = append(children, visit(child)...)
+				}
+			}
+		}
+		if !s.is(o, s.sections) {
+			return children
+		}
+		name := argText(o, 0)
+		if name == "" {
+			name = "@" + o.Type
+		}
+		return []documentSymbol{{
+			Name:           name,
+			Kind:           symbolKindNamespace,
+			Range:          d.span(o.Pos.Offset, d.objectEnd(o)),
+			SelectionRange: d.span(o.Pos.Offset, nameEnd(o)),
+			Children:       children,
+		}}
+	}
+	if d.syn == nil {
+		return []documentSymbol{}
+	}
+	res := visit(d.syn)
+	if res == nil {
+		return []documentSymbol{}
+	}
+	return res
+}
+
+// format returns an edit that replaces the whole document with its formatted source, or no edits if it is not valid.
+func (s *lspServer) format(d *document) []textEdit {
+	if d.syn == nil {
+		return []textEdit{}
+	}
+	return []textEdit{{
+		Range:   d.span(0, len(d.text)),
+		NewText: obtext.FormatSynSource(d.syn) + "\n",
+	}}
+}
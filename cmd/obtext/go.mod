@@ -0,0 +1,3 @@
+module github.com/JoshPattman/obtext/cmd/obtext
+
+go 1.21.0
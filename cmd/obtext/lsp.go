@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/JoshPattman/obtext"
+	"github.com/JoshPattman/obtext/markup"
+)
+
+// lspServer is a language server for .obt files, which uses the markup semantics.
+type lspServer struct {
+	conn *rpcConn
+	reg  *obtext.Registry
+	// schemas is the documentation of each object, including aliases.
+	schemas map[string]obtext.ObjectSchema
+	// sections are the objects that are shown in the outline, and whose first arg is their title.
+	sections []string
+	// labels are the objects that define a label with their first arg, and refs are the objects that refer to one with theirs.
+	labels, refs []string
+	docs         map[string]*document
+	shutdown     bool
+}
+
+// runLSP runs the language server on stdin and stdout until the client exits.
+func runLSP(args []string) error {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	sections := fs.String("sections", "section,subsection", "Comma separated objects to show in the outline, using their first arg as the title")
+	labels := fs.String("labels", "label", "Comma separated objects that define a label with their first arg")
+	refs := fs.String("refs", "ref", "Comma separated objects that refer to a label with their first arg, for go-to-definition")
+	fs.Parse(args)
+
+	s := newLSPServer(markup.NewRegistry(), os.Stdin, os.Stdout)
+	s.sections = strings.Split(*sections, ",")
+	s.labels = strings.Split(*labels, ",")
+	s.refs = strings.Split(*refs, ",")
+	return s.serve()
+}
+
+func newLSPServer(reg *obtext.Registry, r io.Reader, w io.Writer) *lspServer {
+	s := &lspServer{
+		conn:    newRPCConn(r, w),
+		reg:     reg,
+		schemas: make(map[string]obtext.ObjectSchema),
+		docs:    make(map[string]*document),
+	}
+	for _, schema := range reg.Schema() {
+		s.schemas[schema.Name] = schema
+		for _, a := range schema.Aliases {
+			s.schemas[a] = schema
+		}
+	}
+	return s
+}
+
+// serve handles messages until the client sends exit or closes the connection.
+func (s *lspServer) serve() error {
+	for {
+		msg, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exited without shutting down")
+			}
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			// Notifications have no response, so errors are only logged
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "obtext lsp: %s: %s\n", msg.Method, err)
+			}
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: msg.ID, Result: result}
+		if err != nil {
+			var rpcErr *rpcError
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &rpcError{Code: rpcInternalError, Message: err.Error()}
+			}
+			resp.Result, resp.Error = nil, rpcErr
+		}
+		if err := s.conn.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles a single request or notification, returning the result for requests.
+func (s *lspServer) handle(msg *rpcMessage) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				// Incremental document sync, so that only the edited part of the document is parsed again
+				"textDocumentSync":           2,
+				"completionProvider":         map[string]any{"triggerCharacters": []string{"@"}},
+				"hoverProvider":              true,
+				"documentSymbolProvider":     true,
+				"definitionProvider":         true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "obtext"},
+		}, nil
+	case "initialized", "$/cancelRequest", "$/setTrace", "textDocument/didSave":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(newDocument(params.TextDocument.URI, params.TextDocument.Text))
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		d, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range params.ContentChanges {
+			if c.Range == nil {
+				d = newDocument(d.uri, c.Text)
+			} else {
+				d = d.edit(obtext.SynEdit{Start: d.offset(c.Range.Start), End: d.offset(c.Range.End), Text: c.Text})
+			}
+		}
+		return nil, s.update(d)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.conn.write(rpcNotification{
+			JSONRPC: "2.0",
+			Method:  "textDocument/publishDiagnostics",
+			Params:  publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []diagnostic{}},
+		})
+	case "textDocument/completion":
+		return s.completion(), nil
+	case "textDocument/hover":
+		return withPosition(s, msg, s.hover)
+	case "textDocument/definition":
+		return withPosition(s, msg, s.definition)
+	case "textDocument/documentSymbol":
+		return withDocument(s, msg, s.documentSymbols)
+	case "textDocument/formatting":
+		return withDocument(s, msg, s.format)
+	}
+	if strings.HasPrefix(msg.Method, "$/") {
+		// Optional notifications and requests may be ignored
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not supported: " + msg.Method}
+}
+
+// update stores a new version of a document and publishes its diagnostics.
+func (s *lspServer) update(d *document) error {
+	s.docs[d.uri] = d
+	return s.conn.write(rpcNotification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: d.uri, Diagnostics: s.diagnostics(d)},
+	})
+}
+
+func unmarshalParams(msg *rpcMessage, params any) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// document returns the open document with the given uri.
+func (s *lspServer) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "document is not open: " + uri}
+	}
+	return d, nil
+}
+
+// withDocument calls a handler that only needs the document of the request.
+func withDocument[T any](s *lspServer, msg *rpcMessage, f func(d *document) T) (any, error) {
+	var params documentParams
+	if err := unmarshalParams(msg, &params); err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return f(d), nil
+}
+
+// withPosition calls a handler that needs the document and byte offset of the request.
+func withPosition[T any](s *lspServer, msg *rpcMessage, f func(d *document, offset int) T) (any, error) {
+	var params textDocumentPositionParams
+	if err := unmarshalParams(msg, &params); err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return f(d, d.offset(params.Position)), nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/JoshPattman/obtext"
+	"github.com/JoshPattman/obtext/markup"
+)
+
+// testMessage is any message sent by the server.
+type testMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// testClient talks to a language server over in-memory pipes.
+type testClient struct {
+	t      *testing.T
+	conn   *rpcConn
+	nextID int
+	done   chan error
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	s := newLSPServer(markup.NewRegistry(), serverR, serverW)
+	c := &testClient{t: t, conn: newRPCConn(clientR, clientW), done: make(chan error, 1)}
+	go func() {
+		c.done <- s.serve()
+		serverW.Close()
+	}()
+	t.Cleanup(func() { clientW.Close() })
+	return c
+}
+
+// read reads the next message from the server.
+func (c *testClient) read() testMessage {
+	c.t.Helper()
+	header, err := c.conn.r.ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatalf("failed to read a message: %v", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.conn.r.R, body); err != nil {
+		c.t.Fatal(err)
+	}
+	var msg testMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatalf("invalid message %s: %v", body, err)
+	}
+	return msg
+}
+
+// notify sends a notification to the server.
+func (c *testClient) notify(method string, params any) {
+	c.t.Helper()
+	if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "method": method, "params": params}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request to the server and decodes the result of its response into result.
+func (c *testClient) call(method string, params any, result any) {
+	c.t.Helper()
+	c.nextID++
+	if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params}); err != nil {
+		c.t.Fatal(err)
+	}
+	msg := c.read()
+	if msg.ID == nil || *msg.ID != c.nextID {
+		c.t.Fatalf("%s: expected the response to request %d, got %+v", method, c.nextID, msg)
+	}
+	if msg.Error != nil {
+		c.t.Fatalf("%s: %v", method, msg.Error)
+	}
+	if err := json.Unmarshal(msg.Result, result); err != nil {
+		c.t.Fatalf("%s: invalid result %s: %v", method, msg.Result, err)
+	}
+}
+
+// diagnostics reads the next message, which must be the diagnostics of the document.
+func (c *testClient) diagnostics(uri string) []diagnostic {
+	c.t.Helper()
+	msg := c.read()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected diagnostics, got %+v", msg)
+	}
+	var params publishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		c.t.Fatal(err)
+	}
+	if params.URI != uri {
+		c.t.Errorf("got diagnostics for %s, want %s", params.URI, uri)
+	}
+	return params.Diagnostics
+}
+
+func TestLSP(t *testing.T) {
+	const uri = "file:///test.obt"
+	c := newTestClient(t)
+
+	var init struct {
+		Capabilities map[string]any `json:"capabilities"`
+	}
+	c.call("initialize", map[string]any{}, &init)
+	if init.Capabilities["textDocumentSync"] != float64(2) || init.Capabilities["hoverProvider"] != true {
+		t.Errorf("got capabilities %v", init.Capabilities)
+	}
+	c.notify("initialized", map[string]any{})
+
+	// Open a document with a misspelt object on its second line
+	text := "@doc{\n  @para{Hello @bodl{world}}\n}"
+	c.notify("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{URI: uri, Version: 1, Text: text}})
+	diags := c.diagnostics(uri)
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(diags), diags)
+	}
+	expectedRange := lspRange{Start: lspPosition{Line: 1, Character: 14}, End: lspPosition{Line: 1, Character: 19}}
+	if diags[0].Range != expectedRange || diags[0].Severity != severityError || !strings.Contains(diags[0].Message, "did you mean @bold?") {
+		t.Errorf("got diagnostic %+v", diags[0])
+	}
+
+	// Fix the spelling with an incremental edit, replacing "dl" with "ld"
+	change := didChangeParams{TextDocument: textDocumentIdentifier{URI: uri}}
+	change.ContentChanges = append(change.ContentChanges, struct {
+		Range *lspRange `json:"range"`
+		Text  string    `json:"text"`
+	}{&lspRange{Start: lspPosition{Line: 1, Character: 17}, End: lspPosition{Line: 1, Character: 19}}, "ld"})
+	c.notify("textDocument/didChange", change)
+	if diags := c.diagnostics(uri); len(diags) != 0 {
+		t.Errorf("got diagnostics after fixing the error: %+v", diags)
+	}
+
+	var completions []completionItem
+	c.call("textDocument/completion", textDocumentPositionParams{TextDocument: textDocumentIdentifier{URI: uri}}, &completions)
+	var img *completionItem
+	for i := range completions {
+		if completions[i].Label == "img" {
+			img = &completions[i]
+		}
+	}
+	if len(completions) != len(markup.NewRegistry().Names()) || img == nil || img.Detail != "@img{caption}{url}[{alt}]" {
+		t.Errorf("got %d completions, with @img as %+v", len(completions), img)
+	}
+
+	// Hover over the fixed @bold
+	var h hover
+	c.call("textDocument/hover", textDocumentPositionParams{TextDocument: textDocumentIdentifier{URI: uri}, Position: lspPosition{Line: 1, Character: 16}}, &h)
+	if !strings.Contains(h.Contents.Value, "@bold{text}") || h.Range != expectedRange {
+		t.Errorf("got hover %+v", h)
+	}
+
+	var edits []textEdit
+	c.call("textDocument/formatting", documentParams{TextDocument: textDocumentIdentifier{URI: uri}}, &edits)
+	fixed := "@doc{\n  @para{Hello @bold{world}}\n}"
+	syn, err := obtext.ParseSynString(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedEdit := textEdit{Range: lspRange{End: lspPosition{Line: 2, Character: 1}}, NewText: obtext.FormatSynSource(syn) + "\n"}
+	if len(edits) != 1 || edits[0] != expectedEdit {
+		t.Errorf("got edits %+v, want %+v", edits, expectedEdit)
+	}
+
+	// Break the syntax by deleting the last brace, which is reported at the brace that is never closed
+	change.ContentChanges[0].Range = &lspRange{Start: lspPosition{Line: 2, Character: 0}, End: lspPosition{Line: 2, Character: 1}}
+	change.ContentChanges[0].Text = ""
+	c.notify("textDocument/didChange", change)
+	if diags := c.diagnostics(uri); len(diags) != 1 || diags[0].Range.Start != (lspPosition{Line: 0, Character: 4}) {
+		t.Errorf("got diagnostics %+v for a syntax error", diags)
+	}
+	c.call("textDocument/formatting", documentParams{TextDocument: textDocumentIdentifier{URI: uri}}, &edits)
+	if len(edits) != 0 {
+		t.Errorf("got edits %+v for a document that does not parse", edits)
+	}
+
+	var null json.RawMessage
+	c.call("shutdown", nil, &null)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("the server failed: %v", err)
+	}
+}
+
+func TestLSPErrors(t *testing.T) {
+	c := newTestClient(t)
+	for method, code := range map[string]int{
+		"textDocument/hover":      rpcInvalidParams,
+		"textDocument/unknown":    rpcMethodNotFound,
+		"textDocument/formatting": rpcInvalidParams,
+	} {
+		c.nextID++
+		params := documentParams{TextDocument: textDocumentIdentifier{URI: "file:///closed.obt"}}
+		if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params}); err != nil {
+			t.Fatal(err)
+		}
+		msg := c.read()
+		if msg.Error == nil || msg.Error.Code != code {
+			t.Errorf("%s: got %+v, want error code %d", method, msg, code)
+		}
+	}
+	// Exiting without shutting down is an error
+	c.notify("exit", nil)
+	if err := <-c.done; err == nil || !strings.Contains(fmt.Sprint(err), "without shutting down") {
+		t.Errorf("got %v", err)
+	}
+}
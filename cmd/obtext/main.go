@@ -0,0 +1,31 @@
+// Command obtext provides tools for working with obtext files.
+//
+// Usage:
+//
+//	obtext lsp [flags]
+//
+// The lsp command runs a language server for .obt files, which speaks the Language Server Protocol over stdin and stdout.
+// Run `obtext lsp -h` to see its flags.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: obtext <command> [flags]\n\ncommands:\n  lsp    run a language server over stdio")
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "lsp":
+		if err := runLSP(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "obtext lsp:", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		os.Exit(2)
+	}
+}
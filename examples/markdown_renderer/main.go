@@ -17,11 +17,13 @@ func main() {
 	var secondaryOutputFileName string
 	var prettyPrint bool
 	var generateHtml bool
+	var lenient bool
 
 	flag.StringVar(&inputFileName, "i", "", "The input file (.obt) to read")
 	flag.StringVar(&outputFileName, "o", "", "The output file (.md) to write")
 	flag.BoolVar(&prettyPrint, "p", false, "Pretty print the parsed obt ast to output")
 	flag.BoolVar(&generateHtml, "h", false, "Generate an html file as well as a markdown file")
+	flag.BoolVar(&lenient, "l", false, "Print objects that fail to render as warnings, instead of failing")
 
 	flag.Parse()
 
@@ -57,12 +59,20 @@ func main() {
 	}
 
 	// Try to parse the syntax tree into a semantic tree (the semantics parsing step)
-	st, err := obtext.ParseSem(ast, markup.Semantics)
+	sourceMap := &obtext.SourceMap{}
+	st, err := obtext.ParseSem(ast, markup.Semantics, obtext.WithSourceMap(sourceMap))
 	if err != nil {
 		fmt.Println("Failed to process semantics:", err)
 		os.Exit(1)
 	}
 
+	// Render errors include where the failing object is in the input file, and are only warnings if requested
+	var warnings []*markup.RenderError
+	renderOpts := []markup.RenderOption{markup.WithSourceMap(sourceMap)}
+	if lenient {
+		renderOpts = append(renderOpts, markup.WithWarnings(&warnings))
+	}
+
 	// Create the output file
 	outputFile, err := os.Create(outputFileName)
 	if err != nil {
@@ -72,8 +82,11 @@ func main() {
 	defer outputFile.Close()
 
 	// Generate the markdown from the semantic tree
-	md := markup.RenderMarkdown(st)
-	fmt.Fprint(outputFile, md)
+	if err := markup.WriteMarkdown(outputFile, st, renderOpts...); err != nil {
+		fmt.Println("Failed to render markdown:", err)
+		os.Exit(1)
+	}
+	printWarnings(&warnings)
 
 	// Success!
 	fmt.Println("Successfully wrote markdown to", outputFileName)
@@ -89,10 +102,21 @@ func main() {
 		defer htmlOutputFile.Close()
 
 		// Generate the html from the semantic tree
-		html := markup.RenderHTML(st, "")
-		fmt.Fprint(htmlOutputFile, html)
+		if err := markup.WriteHTML(htmlOutputFile, st, renderOpts...); err != nil {
+			fmt.Println("Failed to render html:", err)
+			os.Exit(1)
+		}
+		printWarnings(&warnings)
 
 		// Success!
 		fmt.Println("Successfully wrote html to", secondaryOutputFileName)
 	}
 }
+
+// printWarnings prints and clears the warnings from rendering.
+func printWarnings(warnings *[]*markup.RenderError) {
+	for _, w := range *warnings {
+		fmt.Println("Warning:", w)
+	}
+	*warnings = nil
+}
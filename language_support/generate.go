@@ -0,0 +1,25 @@
+//go:build ignore
+
+// This program regenerates the nano and VSCode syntax definitions from the markup semantics.
+// Run it from the root of the repository with `go generate` or `go run ./language_support/generate.go`.
+package main
+
+import (
+	"os"
+
+	"github.com/JoshPattman/obtext/markup"
+)
+
+func main() {
+	reg := markup.NewRegistry()
+	if err := os.WriteFile("language_support/nano/obtext.nanorc", []byte(reg.NanoSyntax()), 0644); err != nil {
+		panic(err)
+	}
+	grammar, err := reg.TextMateGrammar()
+	if err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile("language_support/vscode/obtext/syntaxes/obtext.tmLanguage.json", grammar, 0644); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,28 @@
+package markup
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLanguageSupportUpToDate(t *testing.T) {
+	// The syntax definitions are generated from the markup registry, so they must be regenerated with `go generate` whenever it changes
+	reg := NewRegistry()
+	grammar, err := reg.TextMateGrammar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"../language_support/nano/obtext.nanorc":                            reg.NanoSyntax(),
+		"../language_support/vscode/obtext/syntaxes/obtext.tmLanguage.json": string(grammar),
+	}
+	for path, expected := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("%s is out of date, run `go generate` in the root of the repository", path)
+		}
+	}
+}
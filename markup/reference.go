@@ -0,0 +1,138 @@
+package markup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JoshPattman/obtext"
+)
+
+// ReferenceDoc generates a reference document (a cheat-sheet) for every object in the registry, using the markup nodes.
+// The descriptions come from Registry.Schema, so nodes that implement obtext.DescribedSemNode give the most useful output.
+// The document can be rendered with any markup renderer, or written as obtext with ReferenceSource.
+func ReferenceDoc(reg *obtext.Registry, title string) *DocSemNode {
+	objects := make([]obtext.SemNode, 0)
+	for _, s := range reg.Schema() {
+		objects = append(objects, referenceSection(s))
+	}
+	return &DocSemNode{obtext.SingleArgSemNode{Content: refBlock(
+		&SectionSemNode{obtext.DualArgSemNode{
+			Arg1: refText(title),
+			Arg2: refBlock(objects...),
+		}},
+	)}}
+}
+
+// ReferenceSource generates the reference document for the registry as obtext source.
+func ReferenceSource(reg *obtext.Registry, title string) (string, error) {
+	syn, err := reg.UnparseSem(ReferenceDoc(reg, title))
+	if err != nil {
+		return "", err
+	}
+	return obtext.FormatSynSource(syn), nil
+}
+
+// ReferenceMarkdown generates the reference document for the registry as markdown.
+func ReferenceMarkdown(reg *obtext.Registry, title string) (string, error) {
+	return RenderMarkdownErr(ReferenceDoc(reg, title))
+}
+
+// ReferenceHTML generates the reference document for the registry as html.
+func ReferenceHTML(reg *obtext.Registry, title string) (string, error) {
+	return RenderHTMLErr(ReferenceDoc(reg, title), "")
+}
+
+// referenceSection generates the subsection describing a single object.
+func referenceSection(s obtext.ObjectSchema) obtext.SemNode {
+	blocks := make([]obtext.SemNode, 0)
+	if s.Summary != "" {
+		blocks = append(blocks, refPara(&obtext.TextSemNode{Text: s.Summary}))
+	}
+	blocks = append(blocks, refPara(&obtext.TextSemNode{Text: "Usage: "}, refCode(s.Usage())))
+	if len(s.Aliases) > 0 {
+		aliases := make([]string, len(s.Aliases))
+		for i, a := range s.Aliases {
+			aliases[i] = "@" + a
+		}
+		blocks = append(blocks, refPara(&obtext.TextSemNode{Text: "Aliases: " + strings.Join(aliases, ", ")}))
+	}
+	if len(s.Args) > 0 {
+		items := make([]*obtext.ContentBlockSemNode, len(s.Args))
+		for i, a := range s.Args {
+			items[i] = referenceArg(a)
+		}
+		blocks = append(blocks, refPara(&obtext.TextSemNode{Text: "Args:"}), &UlSemNode{obtext.ListArgSemNode{Contents: items}})
+	}
+	if len(s.Parents) > 0 {
+		blocks = append(blocks, refPara(&obtext.TextSemNode{Text: "Only inside: " + referenceNames(s.Parents)}))
+	}
+	if len(s.Examples) > 0 {
+		items := make([]*obtext.ContentBlockSemNode, len(s.Examples))
+		for i, e := range s.Examples {
+			items[i] = refBlock(refCode(e))
+		}
+		blocks = append(blocks, refPara(&obtext.TextSemNode{Text: "Examples:"}), &UlSemNode{obtext.ListArgSemNode{Contents: items}})
+	}
+	return &SubSectionSemNode{obtext.DualArgSemNode{
+		Arg1: refBlock(refCode("@" + s.Name)),
+		Arg2: refBlock(blocks...),
+	}}
+}
+
+// referenceArg returns the list item describing a single arg.
+func referenceArg(a obtext.ArgSchema) *obtext.ContentBlockSemNode {
+	details := []string{a.Kind}
+	if a.Optional {
+		details = append(details, "optional")
+	}
+	if a.Variadic {
+		details = append(details, "repeatable")
+	}
+	if a.Default != "" {
+		details = append(details, "default "+a.Default)
+	}
+	text := fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+	if a.Description != "" {
+		text += ": " + a.Description
+	}
+	if len(a.Allow) > 0 {
+		text += fmt.Sprintf(" May contain %s.", referenceNames(a.Allow))
+	}
+	return refBlock(refBold(a.Name), &obtext.TextSemNode{Text: text})
+}
+
+// referenceNames formats a list of categories and syntax types from a content model.
+func referenceNames(names []string) string {
+	res := make([]string, len(names))
+	for i, n := range names {
+		switch n {
+		case obtext.TextContent:
+			res[i] = "text"
+		case obtext.RootParent:
+			res[i] = "the root"
+		default:
+			res[i] = n
+		}
+	}
+	return strings.Join(res, ", ")
+}
+
+func refBlock(elements ...obtext.SemNode) *obtext.ContentBlockSemNode {
+	return &obtext.ContentBlockSemNode{Elements: elements}
+}
+
+func refText(text string) *obtext.ContentBlockSemNode {
+	return refBlock(&obtext.TextSemNode{Text: text})
+}
+
+func refPara(elements ...obtext.SemNode) *PSemNode {
+	return &PSemNode{obtext.SingleArgSemNode{Content: refBlock(elements...)}}
+}
+
+func refBold(text string) *BoldSemNode {
+	return &BoldSemNode{obtext.SingleArgSemNode{Content: refText(text)}}
+}
+
+func refCode(text string) *InlineCodeSemNode {
+	return &InlineCodeSemNode{obtext.SingleArgSemNode{Content: refText(text)}}
+}
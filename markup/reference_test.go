@@ -0,0 +1,62 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JoshPattman/obtext"
+)
+
+func TestReferenceSource(t *testing.T) {
+	reg := NewRegistry()
+	src, err := ReferenceSource(reg, "Reference")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The generated source must be valid obtext that describes every object
+	sem, err := reg.Parse(mustParseSyn(t, src))
+	if err != nil {
+		t.Fatalf("the reference source does not parse: %v\n%s", err, src)
+	}
+	schemas := reg.Schema()
+	subsections := obtext.FindAll[*SubSectionSemNode](sem)
+	if len(subsections) != len(schemas) {
+		t.Fatalf("got %d subsections, want one for each of the %d objects", len(subsections), len(schemas))
+	}
+	for i, s := range schemas {
+		if got := plainText(subsections[i].Arg1); got != "@"+s.Name {
+			t.Errorf("subsection %d has title %q, want @%s", i, got, s.Name)
+		}
+		if !strings.Contains(plainText(subsections[i].Arg2), "Usage: "+s.Usage()) {
+			t.Errorf("the subsection for @%s does not include its usage %s", s.Name, s.Usage())
+		}
+	}
+}
+
+func TestReferenceImage(t *testing.T) {
+	var img obtext.ObjectSchema
+	for _, s := range NewRegistry().Schema() {
+		if s.Name == "img" {
+			img = s
+		}
+	}
+	if img.MinArgs != 2 || img.MaxArgs != 3 || len(img.Args) != 3 || !img.Args[2].Optional {
+		t.Fatalf("got %+v", img)
+	}
+	md, err := ReferenceMarkdown(NewRegistry(), "Reference")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"# Reference", "## `@img`", "Usage: `" + img.Usage() + "`", "`@img{A cat}{cat.png}`"} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("the markdown reference does not contain %q", expected)
+		}
+	}
+	html, err := ReferenceHTML(NewRegistry(), "Reference")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, "<h1>Reference</h1>") {
+		t.Errorf("the html reference does not contain the title:\n%s", html)
+	}
+}
@@ -0,0 +1,151 @@
+package markup
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/JoshPattman/obtext"
+)
+
+// htmlTextEscaper escapes text between html tags, where quotes do not need to be escaped.
+// Attributes are always quoted, and are escaped with html.EscapeString, which also escapes quotes.
+var htmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// DefaultURLSchemes are the URL schemes that the html renderer allows in links, images and videos, unless it is given others.
+// The markdown renderer allows any scheme, unless it is given these or others with WithMarkdownURLSchemes.
+// URLs without a scheme, such as relative paths, are always allowed.
+var DefaultURLSchemes = []string{"http", "https", "mailto"}
+
+// unsafeURL is written in place of a URL whose scheme is not allowed, so the link goes nowhere.
+const unsafeURL = "#"
+
+// checkURL returns an error if the URL has a scheme that is not in schemes, or cannot be parsed, such as one containing control characters.
+func checkURL(link string, schemes []string) error {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %w", link, err)
+	}
+	if u.Scheme == "" {
+		return nil
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return nil
+		}
+	}
+	return fmt.Errorf("url %q has a scheme that is not allowed: %s", link, u.Scheme)
+}
+
+// plainText returns the text of a node and all of its children, without any formatting, for use where markup is not allowed, such as attributes.
+func plainText(n obtext.SemNode) string {
+	sb := &strings.Builder{}
+	obtext.Walk(n, func(n, _ obtext.SemNode) bool {
+		if t, ok := n.(*obtext.TextSemNode); ok {
+			sb.WriteString(t.Text)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// markdownInlineSpecial are the characters that can start inline formatting, such as emphasis, code or links, anywhere in markdown text.
+// '|' is included as it separates the cells of a table in GitHub flavoured markdown.
+const markdownInlineSpecial = "\\`*_[]<~|"
+
+// markdownLineSpecial are the characters that can start a block, such as a heading or list, at the start of a line of markdown.
+const markdownLineSpecial = "#>-+="
+
+// escapeMarkdown escapes text so that markdown shows it as written, rather than treating any of it as formatting.
+func escapeMarkdown(s string) string {
+	sb := &strings.Builder{}
+	lineStart := true
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '\n' || (lineStart && (ch == ' ' || ch == '\t')):
+			// Blocks can be indented a little, so the start of the line continues until the first non-space
+			sb.WriteByte(ch)
+			lineStart = true
+			continue
+		case lineStart && strings.IndexByte(markdownLineSpecial, ch) >= 0:
+			sb.WriteByte('\\')
+		case lineStart && isDigit(ch):
+			// A number followed by '.' or ')' starts an ordered list
+			j := i
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			sb.WriteString(s[i:j])
+			if j < len(s) && (s[j] == '.' || s[j] == ')') {
+				sb.WriteByte('\\')
+			}
+			i = j - 1
+			lineStart = false
+			continue
+		case strings.IndexByte(markdownInlineSpecial, ch) >= 0:
+			sb.WriteByte('\\')
+		case ch == '&' && i+1 < len(s) && (isLetter(s[i+1]) || s[i+1] == '#'):
+			// Only escape '&' where it could start an entity, such as &amp;
+			sb.WriteByte('\\')
+		}
+		lineStart = false
+		sb.WriteByte(ch)
+	}
+	return sb.String()
+}
+
+// markdownNewlines matches a line break along with the whitespace around it.
+var markdownNewlines = regexp.MustCompile(`[ \t]*\r?\n[ \t\r\n]*`)
+
+// singleLine replaces each line break in s with a space, for text that must stay on one line, such as a markdown heading or list item.
+func singleLine(s string) string {
+	return markdownNewlines.ReplaceAllString(s, " ")
+}
+
+// markdownURLEscaper percent-encodes the characters that would end a markdown link destination early, or be read as an escape.
+var markdownURLEscaper = strings.NewReplacer(" ", "%20", "\n", "%0A", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E", "\\", "%5C")
+
+// markdownCodeSpan returns the code as a markdown code span, using more backticks than any run of backticks in the code.
+func markdownCodeSpan(code string) string {
+	fence := strings.Repeat("`", longestRun(code, '`')+1)
+	// Markdown removes one space from each end of a code span, which also allows it to start or end with a backtick
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") || (strings.HasPrefix(code, " ") && strings.HasSuffix(code, " ")) {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+// markdownCodeFence returns the backticks to put around a markdown code block, which must be longer than any run of backticks in the code.
+func markdownCodeFence(code string) string {
+	n := longestRun(code, '`') + 1
+	if n < 3 {
+		n = 3
+	}
+	return strings.Repeat("`", n)
+}
+
+// longestRun returns the length of the longest run of ch in s.
+func longestRun(s string, ch byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != ch {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
@@ -1,64 +1,189 @@
 package markup
 
 import (
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/JoshPattman/obtext"
 )
 
+// defaultHTMLRenderer is used by RenderHTML and WriteHTML.
+var defaultHTMLRenderer = NewHTMLRenderer()
+
 // RenderHTML takes a semantic tree using nodes from the markup package and generates an html string from it.
-// To customise the html rendering, you can create a copy of this function in your codebase - its only small.
-func RenderHTML(t obtext.SemNode, indent string) string {
-	switch t := t.(type) {
-	case *obtext.ContentBlockSemNode:
-		out := ""
-		for _, e := range t.Elements {
-			out += RenderHTML(e, indent)
+// To customise the html rendering, use NewHTMLRenderer and replace or add render functions.
+// Nodes that fail to render, such as embedded code whose file cannot be read, are replaced by their error message in the output.
+//
+// Deprecated: use RenderHTMLErr, which returns an error instead of writing it into the output.
+func RenderHTML(t obtext.SemNode, indent string, opts ...RenderOption) string {
+	out, _ := RenderHTMLErr(t, indent, inlineErrors(opts, html.EscapeString)...)
+	return out
+}
+
+// RenderHTMLErr takes a semantic tree using nodes from the markup package and generates an html string from it,
+// returning an error if a node fails to render. Use WithWarnings to render the rest of the document anyway.
+func RenderHTMLErr(t obtext.SemNode, indent string, opts ...RenderOption) (string, error) {
+	sb := &strings.Builder{}
+	err := defaultHTMLRenderer.render(sb, t, indent, newRenderOptions(opts))
+	return sb.String(), err
+}
+
+// WriteHTML is like RenderHTMLErr, but writes the html to w as it is generated, which is much faster for large documents.
+func WriteHTML(w io.Writer, t obtext.SemNode, opts ...RenderOption) error {
+	return defaultHTMLRenderer.Render(w, t, opts...)
+}
+
+// HTMLOption is an option that changes the behaviour of NewHTMLRenderer.
+type HTMLOption func(*htmlConfig)
+
+type htmlConfig struct {
+	schemes []string
+	strict  bool
+}
+
+// WithURLSchemes sets the URL schemes that links, images and videos may use, instead of DefaultURLSchemes.
+// URLs without a scheme, such as relative paths, are always allowed.
+func WithURLSchemes(schemes ...string) HTMLOption {
+	return func(cfg *htmlConfig) {
+		cfg.schemes = schemes
+	}
+}
+
+// WithStrictHTML makes the renderer suitable for documents from untrusted users.
+// URLs with a scheme that is not allowed are errors, rather than being replaced with a link to nowhere,
+// and embedded code is an error, as it would read files from the machine doing the rendering.
+func WithStrictHTML() HTMLOption {
+	return func(cfg *htmlConfig) {
+		cfg.strict = true
+	}
+}
+
+// url returns the URL to write for a link, image or video, or an error if it is not allowed in strict mode.
+func (cfg *htmlConfig) url(link string) (string, error) {
+	if err := checkURL(link, cfg.schemes); err != nil {
+		if cfg.strict {
+			return "", err
+		}
+		return unsafeURL, nil
+	}
+	return link, nil
+}
+
+// NewHTMLRenderer returns a renderer that generates html from the markup nodes.
+// Nodes that it does not know are highlighted with a <mark> tag, which can be changed by setting the Fallback.
+// All text is escaped, and URLs whose scheme is not in DefaultURLSchemes are replaced with "#", which can be changed with the options.
+// Strings written by custom render functions are not escaped, so should be passed through html.EscapeString if they come from the document.
+func NewHTMLRenderer(opts ...HTMLOption) *Renderer {
+	cfg := &htmlConfig{schemes: DefaultURLSchemes}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	r := NewRenderer()
+	r.Fallback = highlightFallback
+	r.Handle(obtext.TextContent, func(c *RenderContext, n obtext.SemNode) error {
+		return c.Print(c.Indent, htmlTextEscaper.Replace(n.(*obtext.TextSemNode).Text))
+	})
+	HandleNode(r, func(c *RenderContext, t *DocSemNode) error {
+		return c.Print(c.Indent, t.Content)
+	})
+	HandleNode(r, func(c *RenderContext, t *SectionSemNode) error {
+		if err := c.WithIndent("").Print("\n"+c.Indent+"<h1>", t.Arg1, "</h1>\n"); err != nil {
+			return err
+		}
+		return c.WithIndent(c.Indent + "\t").Render(t.Arg2)
+	})
+	HandleNode(r, func(c *RenderContext, t *SubSectionSemNode) error {
+		if err := c.WithIndent("").Print("\n"+c.Indent+"<h2>", t.Arg1, "</h2>\n"); err != nil {
+			return err
 		}
-		return out
-	case *obtext.TextSemNode:
-		return indent + t.Text
-	case *DocSemNode:
-		return indent + RenderHTML(t.Content, indent)
-	case *SectionSemNode:
-		return "\n" + indent + "<h1>" + RenderHTML(t.Arg1, "") + "</h1>\n" + RenderHTML(t.Arg2, indent+"\t")
-	case *SubSectionSemNode:
-		return "\n" + indent + "<h2>" + RenderHTML(t.Arg1, "") + "</h2>\n" + RenderHTML(t.Arg2, indent+"\t")
-	case *PSemNode:
-		return "\n" + indent + "<p>" + RenderHTML(t.Content, "") + "</p>\n"
-	case *BoldSemNode:
-		return "<b>" + RenderHTML(t.Content, "") + "</b>"
-	case *ItalicSemNode:
-		return "<i>" + RenderHTML(t.Content, "") + "</i>"
-	case *ImageSemNode:
-		return "\n" + indent + fmt.Sprintf("<img alt=\"%s\" src=\"%s\" width=50%% align=\"center\"/>\n", RenderHTML(t.CaptionContent, ""), t.Link)
-	case *EmbeddedCodeSemNode:
-		f, err := os.Open(t.Arg2)
+		return c.WithIndent(c.Indent + "\t").Render(t.Arg2)
+	})
+	HandleNode(r, func(c *RenderContext, t *PSemNode) error {
+		return c.WithIndent("").Print("\n"+c.Indent+"<p>", t.Content, "</p>\n")
+	})
+	HandleNode(r, func(c *RenderContext, t *BoldSemNode) error {
+		return c.WithIndent("").Print("<b>", t.Content, "</b>")
+	})
+	HandleNode(r, func(c *RenderContext, t *ItalicSemNode) error {
+		return c.WithIndent("").Print("<i>", t.Content, "</i>")
+	})
+	HandleNode(r, func(c *RenderContext, t *ImageSemNode) error {
+		link, err := cfg.url(t.Link)
 		if err != nil {
-			return fmt.Sprintf("Failed to open file: %s", err)
+			return err
 		}
-		defer f.Close()
-		data, err := io.ReadAll(f)
+		alt := htmlAlt(t.CaptionedMediaSemNode)
+		_, err = fmt.Fprintf(c, "\n%s<img alt=\"%s\" src=\"%s\" width=50%% align=\"center\"/>\n", c.Indent, html.EscapeString(alt), html.EscapeString(link))
+		return err
+	})
+	HandleNode(r, func(c *RenderContext, t *VideoSemNode) error {
+		link, err := cfg.url(t.Link)
 		if err != nil {
-			return fmt.Sprintf("Failed to read file: %s", err)
+			return err
 		}
-		return fmt.Sprintf("\n<pre><code>%s</code></pre>\n", data)
-	case *InlineCodeSemNode:
-		return "<code>" + RenderHTML(t.Content, "") + "</code>"
-	case *UlSemNode:
-		out := "\n" + indent + "<ul>\n"
-		for _, e := range t.Contents {
-			out += indent + "\t<li>" + RenderHTML(e, "") + "</li>\n"
+		alt := htmlAlt(t.CaptionedMediaSemNode)
+		_, err = fmt.Fprintf(c, "\n%s<video title=\"%s\" src=\"%s\" width=50%% controls></video>\n", c.Indent, html.EscapeString(alt), html.EscapeString(link))
+		return err
+	})
+	HandleNode(r, func(c *RenderContext, t *EmbeddedCodeSemNode) error {
+		if cfg.strict {
+			return errors.New("embedded code is not allowed in strict mode, as it reads a file")
 		}
-		return out + indent + "</ul>"
-	case *OlSemNode:
-		out := "\n" + indent + "<ol>\n"
-		for _, e := range t.Contents {
-			out += indent + "\t<li>" + RenderHTML(e, "") + "</li>\n"
+		data, err := readCode(t)
+		if err != nil {
+			return err
+		}
+		return c.Print("\n<pre><code>", htmlTextEscaper.Replace(string(data)), "</code></pre>\n")
+	})
+	HandleNode(r, func(c *RenderContext, t *InlineCodeSemNode) error {
+		return c.WithIndent("").Print("<code>", t.Content, "</code>")
+	})
+	HandleNode(r, func(c *RenderContext, t *UlSemNode) error {
+		return htmlList(c, "ul", t.Contents)
+	})
+	HandleNode(r, func(c *RenderContext, t *OlSemNode) error {
+		return htmlList(c, "ol", t.Contents)
+	})
+	HandleNode(r, func(c *RenderContext, t *LinkSemNode) error {
+		link, err := cfg.url(t.Link)
+		if err != nil {
+			return err
 		}
-		return out + indent + "</ol>"
+		return c.WithIndent("").Print("<a href=\""+html.EscapeString(link)+"\">", t.CaptionContent, "</a>")
+	})
+	return r
+}
+
+// htmlAlt returns the alternative text of an image or video, which is the text of the caption if no alternative text was given.
+// It must be escaped before it is written.
+func htmlAlt(t obtext.CaptionedMediaSemNode) string {
+	if t.Alt != "" {
+		return t.Alt
+	}
+	return plainText(t.CaptionContent)
+}
+
+// htmlList renders a list with the given tag, with each item on its own line.
+func htmlList(c *RenderContext, tag string, items []*obtext.ContentBlockSemNode) error {
+	c.WriteString("\n" + c.Indent + "<" + tag + ">\n")
+	for _, e := range items {
+		if err := c.WithIndent("").Print(c.Indent+"\t<li>", e, "</li>\n"); err != nil {
+			return err
+		}
+	}
+	return c.Print(c.Indent + "</" + tag + ">")
+}
+
+// readCode reads the file of an embedded code node.
+func readCode(t *EmbeddedCodeSemNode) ([]byte, error) {
+	f, err := os.Open(t.Arg2)
+	if err != nil {
+		return nil, err
 	}
-	panic(fmt.Sprintf("node type %T was not included in renderer", t))
+	defer f.Close()
+	return io.ReadAll(f)
 }
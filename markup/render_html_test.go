@@ -0,0 +1,99 @@
+package markup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JoshPattman/obtext"
+)
+
+func TestHTMLEscaping(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected string
+	}{
+		{"@para{<script>alert(1)</script> & more}", "<p>&lt;script&gt;alert(1)&lt;/script&gt; &amp; more</p>"},
+		{"@para{\"quotes\" are fine in text}", "<p>\"quotes\" are fine in text</p>"},
+		{"@img{A \"cat\"}{cat.png?a=1&b=2}", "<img alt=\"A &#34;cat&#34;\" src=\"cat.png?a=1&amp;b=2\""},
+		{"@img{A cat}{cat.png}{\"><script>}", "<img alt=\"&#34;&gt;&lt;script&gt;\" src=\"cat.png\""},
+		{"@vid{<b>}{dog.mp4}", "<video title=\"&lt;b&gt;\" src=\"dog.mp4\""},
+		{"@para{@link{<b>}{https://example.com/\"onclick=\"x}}", "<a href=\"https://example.com/&#34;onclick=&#34;x\">&lt;b&gt;</a>"},
+		{"@para{@icode{<b>}}", "<code>&lt;b&gt;</code>"},
+	}
+	for _, c := range cases {
+		sem := mustParseMarkup(t, "@doc{"+c.src+"}")
+		if got := mustRender(t, NewHTMLRenderer(), sem); !strings.Contains(got, c.expected) {
+			t.Errorf("%s: got %q, want it to contain %q", c.src, got, c.expected)
+		}
+	}
+}
+
+func TestHTMLFallbackEscaping(t *testing.T) {
+	unknown := &obtext.UnknownSemNode{Type: `x"><script>alert(1)</script>`, Args: []*obtext.ContentBlockSemNode{{Elements: []obtext.SemNode{&obtext.TextSemNode{Text: "<b>"}}}}}
+	got := mustRender(t, NewHTMLRenderer(), unknown)
+	expected := "<mark title=\"unknown object @x&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;\">&lt;b&gt;</mark>"
+	if got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
+
+func TestHTMLURLSchemes(t *testing.T) {
+	cases := []struct {
+		link    string
+		allowed bool
+	}{
+		{"https://example.com", true},
+		{"HTTP://example.com", true},
+		{"mailto:a@example.com", true},
+		{"relative/path.html", true},
+		{"#anchor", true},
+		{"javascript:alert(1)", false},
+		{"JavaScript:alert(1)", false},
+		{" javascript:alert(1)", false},
+		{"data:text/html,<script>", false},
+		{"java\nscript:alert(1)", false},
+	}
+	for _, c := range cases {
+		link := &LinkSemNode{obtext.CaptionedLinkSemNode{CaptionContent: &obtext.ContentBlockSemNode{}, Link: c.link}}
+		got := mustRender(t, NewHTMLRenderer(), link)
+		if allowed := got != "<a href=\"#\"></a>"; allowed != c.allowed {
+			t.Errorf("%q: got %q, allowed %v, want %v", c.link, got, allowed, c.allowed)
+		}
+		_, err := NewHTMLRenderer(WithStrictHTML()).RenderString(link)
+		if (err == nil) != c.allowed {
+			t.Errorf("%q: got error %v in strict mode", c.link, err)
+		}
+	}
+	// Other schemes can be allowed instead of the defaults
+	r := NewHTMLRenderer(WithURLSchemes("ftp"))
+	sem := mustParseMarkup(t, "@doc{@para{@link{a}{ftp://example.com} @link{b}{https://example.com}}}")
+	if got := mustRender(t, r, sem); !strings.Contains(got, "<a href=\"ftp://example.com\">a</a>") || !strings.Contains(got, "<a href=\"#\">b</a>") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestHTMLStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("if a < b && c {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sem := mustParseMarkup(t, "@doc{@code{go}{"+path+"}}")
+	if got := mustRender(t, NewHTMLRenderer(), sem); !strings.Contains(got, "<pre><code>if a &lt; b &amp;&amp; c {}</code></pre>") {
+		t.Errorf("got %q", got)
+	}
+	// Embedded code reads files, so it is not allowed in strict mode
+	_, err := NewHTMLRenderer(WithStrictHTML()).RenderString(sem)
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) || renderErr.Node.SyntaxType() != "code" {
+		t.Errorf("got %v, want an error for embedded code", err)
+	}
+	// Safe documents render the same in strict mode
+	sem = mustParseMarkup(t, "@doc{@para{a @link{https://example.com}{b}} @img{c}{c.png}}")
+	strict, err := NewHTMLRenderer(WithStrictHTML()).RenderString(sem)
+	if err != nil || strict != mustRender(t, NewHTMLRenderer(), sem) {
+		t.Errorf("got %q, %v in strict mode", strict, err)
+	}
+}
@@ -2,8 +2,9 @@ package markup
 
 import (
 	"fmt"
+	"html"
 	"io"
-	"os"
+	"strings"
 
 	"github.com/JoshPattman/obtext"
 )
@@ -12,61 +13,155 @@ import (
 // However, this may not be supported in all markdown renderers, so you can set this to false to use the standard markdown image syntax.
 var UseHTMLImageRendering = true
 
+// defaultMarkdownRenderer is used by RenderMarkdown and WriteMarkdown.
+var defaultMarkdownRenderer = NewMarkdownRenderer()
+
 // RenderMarkdown takes a semantic tree using nodes from the markup package and generates a markdown string from it.
-// To customise the markdown rendering, you can create a copy of this function in your codebase - its only small.
-func RenderMarkdown(t obtext.SemNode) string {
-	switch t := t.(type) {
-	case *obtext.ContentBlockSemNode:
-		out := ""
-		for _, e := range t.Elements {
-			out += RenderMarkdown(e)
+// To customise the markdown rendering, use NewMarkdownRenderer and replace or add render functions.
+// Nodes that fail to render, such as embedded code whose file cannot be read, are replaced by their error message in the output.
+//
+// Deprecated: use RenderMarkdownErr, which returns an error instead of writing it into the output.
+func RenderMarkdown(t obtext.SemNode, opts ...RenderOption) string {
+	out, _ := RenderMarkdownErr(t, inlineErrors(opts, escapeMarkdown)...)
+	return out
+}
+
+// RenderMarkdownErr takes a semantic tree using nodes from the markup package and generates a markdown string from it,
+// returning an error if a node fails to render. Use WithWarnings to render the rest of the document anyway.
+func RenderMarkdownErr(t obtext.SemNode, opts ...RenderOption) (string, error) {
+	return defaultMarkdownRenderer.RenderString(t, opts...)
+}
+
+// WriteMarkdown is like RenderMarkdownErr, but writes the markdown to w as it is generated, which is much faster for large documents.
+func WriteMarkdown(w io.Writer, t obtext.SemNode, opts ...RenderOption) error {
+	return defaultMarkdownRenderer.Render(w, t, opts...)
+}
+
+// MarkdownOption is an option that changes the behaviour of NewMarkdownRenderer.
+type MarkdownOption func(*markdownConfig)
+
+type markdownConfig struct {
+	// schemes are the allowed URL schemes, or nil to allow any.
+	schemes []string
+}
+
+// WithMarkdownURLSchemes restricts the URL schemes that links, images and videos may use, such as to DefaultURLSchemes.
+// URLs with any other scheme are replaced with "#", and URLs without a scheme, such as relative paths, are always allowed.
+func WithMarkdownURLSchemes(schemes ...string) MarkdownOption {
+	return func(cfg *markdownConfig) {
+		cfg.schemes = schemes
+	}
+}
+
+// url returns the URL to write for a link, image or video, which is "#" if its scheme is not allowed.
+func (cfg *markdownConfig) url(link string) string {
+	if cfg.schemes != nil && checkURL(link, cfg.schemes) != nil {
+		return unsafeURL
+	}
+	return link
+}
+
+// NewMarkdownRenderer returns a renderer that generates markdown from the markup nodes.
+// Nodes that it does not know are highlighted with a html <mark> tag, which can be changed by setting the Fallback.
+// Text is escaped so that it is shown as written, and line breaks in headings, list items, captions and links are replaced with spaces so they do not end early.
+// URLs are written as they are, unless they are restricted with WithMarkdownURLSchemes.
+func NewMarkdownRenderer(opts ...MarkdownOption) *Renderer {
+	cfg := &markdownConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	r := NewRenderer()
+	r.Fallback = highlightFallback
+	r.Handle(obtext.TextContent, func(c *RenderContext, n obtext.SemNode) error {
+		text := n.(*obtext.TextSemNode).Text
+		if c.singleLine {
+			text = singleLine(text)
+		}
+		return c.Print(escapeMarkdown(text))
+	})
+	HandleNode(r, func(c *RenderContext, t *DocSemNode) error {
+		return c.Render(t.Content)
+	})
+	HandleNode(r, func(c *RenderContext, t *SectionSemNode) error {
+		if err := c.withSingleLine().Print("\n# ", t.Arg1, "\n"); err != nil {
+			return err
 		}
-		return out
-	case *obtext.TextSemNode:
-		return t.Text
-	case *DocSemNode:
-		return RenderMarkdown(t.Content)
-	case *SectionSemNode:
-		return "\n# " + RenderMarkdown(t.Arg1) + "\n" + RenderMarkdown(t.Arg2)
-	case *SubSectionSemNode:
-		return "\n## " + RenderMarkdown(t.Arg1) + "\n" + RenderMarkdown(t.Arg2)
-	case *PSemNode:
-		return "\n" + RenderMarkdown(t.Content) + "\n"
-	case *BoldSemNode:
-		return "**" + RenderMarkdown(t.Content) + "**"
-	case *ItalicSemNode:
-		return "*" + RenderMarkdown(t.Content) + "*"
-	case *ImageSemNode:
+		return c.Render(t.Arg2)
+	})
+	HandleNode(r, func(c *RenderContext, t *SubSectionSemNode) error {
+		if err := c.withSingleLine().Print("\n## ", t.Arg1, "\n"); err != nil {
+			return err
+		}
+		return c.Render(t.Arg2)
+	})
+	HandleNode(r, func(c *RenderContext, t *PSemNode) error {
+		return c.Print("\n", t.Content, "\n")
+	})
+	HandleNode(r, func(c *RenderContext, t *BoldSemNode) error {
+		return c.Print("**", t.Content, "**")
+	})
+	HandleNode(r, func(c *RenderContext, t *ItalicSemNode) error {
+		return c.Print("*", t.Content, "*")
+	})
+	HandleNode(r, func(c *RenderContext, t *ImageSemNode) error {
+		link := cfg.url(t.Link)
 		if UseHTMLImageRendering {
-			return fmt.Sprintf("\n<img alt=\"%s\" src=\"%s\" width=50%% align=\"center\"/>\n", RenderHTML(t.CaptionContent, ""), t.Link)
-		} else {
-			return fmt.Sprintf("\n![%s](%s)\n", RenderMarkdown(t.CaptionContent), t.Link)
+			alt := htmlAlt(t.CaptionedMediaSemNode)
+			_, err := fmt.Fprintf(c, "\n<img alt=\"%s\" src=\"%s\" width=50%% align=\"center\"/>\n", html.EscapeString(alt), html.EscapeString(link))
+			return err
 		}
-	case *EmbeddedCodeSemNode:
-		f, err := os.Open(t.Arg2)
-		if err != nil {
-			return fmt.Sprintf("Failed to open file: %s", err)
+		if t.Alt != "" {
+			return c.Print("\n![", escapeMarkdown(singleLine(t.Alt)), "]("+markdownURLEscaper.Replace(link)+")\n")
+		}
+		return c.withSingleLine().Print("\n![", t.CaptionContent, "]("+markdownURLEscaper.Replace(link)+")\n")
+	})
+	HandleNode(r, func(c *RenderContext, t *VideoSemNode) error {
+		link := cfg.url(t.Link)
+		if UseHTMLImageRendering {
+			alt := htmlAlt(t.CaptionedMediaSemNode)
+			_, err := fmt.Fprintf(c, "\n<video title=\"%s\" src=\"%s\" width=50%% controls></video>\n", html.EscapeString(alt), html.EscapeString(link))
+			return err
 		}
-		defer f.Close()
-		data, err := io.ReadAll(f)
+		// Markdown has no syntax for videos, so link to it instead
+		return c.withSingleLine().Print("\n[", t.CaptionContent, "]("+markdownURLEscaper.Replace(link)+")\n")
+	})
+	HandleNode(r, func(c *RenderContext, t *EmbeddedCodeSemNode) error {
+		data, err := readCode(t)
 		if err != nil {
-			return fmt.Sprintf("Failed to read file: %s", err)
+			return err
+		}
+		// Backticks are not allowed in the language of a code block that is fenced with backticks
+		fence := markdownCodeFence(string(data))
+		return c.Print(fence+strings.ReplaceAll(t.Arg1, "`", "")+"\n", string(data), "\n"+fence+"\n")
+	})
+	HandleNode(r, func(c *RenderContext, t *InlineCodeSemNode) error {
+		// Escapes do not work in code spans, so the text is written as is
+		code := plainText(t.Content)
+		if c.singleLine {
+			code = singleLine(code)
 		}
-		return fmt.Sprintf("```%s\n%s\n```\n", t.Arg1, data)
-	case *InlineCodeSemNode:
-		return "`" + RenderMarkdown(t.Content) + "`"
-	case *UlSemNode:
-		out := "\n"
+		return c.Print(markdownCodeSpan(code))
+	})
+	HandleNode(r, func(c *RenderContext, t *UlSemNode) error {
+		c.WriteString("\n")
 		for _, e := range t.Contents {
-			out += " - " + RenderMarkdown(e) + "\n"
+			if err := c.withSingleLine().Print(" - ", e, "\n"); err != nil {
+				return err
+			}
 		}
-		return out
-	case *OlSemNode:
-		out := "\n"
+		return nil
+	})
+	HandleNode(r, func(c *RenderContext, t *OlSemNode) error {
+		c.WriteString("\n")
 		for i, e := range t.Contents {
-			out += fmt.Sprintf(" %d. %s\n", i+1, RenderMarkdown(e))
+			if err := c.withSingleLine().Print(fmt.Sprintf(" %d. ", i+1), e, "\n"); err != nil {
+				return err
+			}
 		}
-		return out
-	}
-	panic(fmt.Sprintf("node type %T was not included in renderer", t))
+		return nil
+	})
+	HandleNode(r, func(c *RenderContext, t *LinkSemNode) error {
+		return c.withSingleLine().Print("[", t.CaptionContent, "]("+markdownURLEscaper.Replace(cfg.url(t.Link))+")")
+	})
+	return r
 }
@@ -0,0 +1,113 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/JoshPattman/obtext"
+)
+
+func TestEscapeMarkdown(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{"plain text, with punctuation.", "plain text, with punctuation."},
+		{"*not emphasis* and _not_ either", `\*not emphasis\* and \_not\_ either`},
+		{"`code` [link](url) <tag> ~~strike~~", "\\`code\\` \\[link\\](url) \\<tag> \\~\\~strike\\~\\~"},
+		{`a \ backslash`, `a \\ backslash`},
+		{"a | b | c", `a \| b \| c`},
+		{"# not a heading", `\# not a heading`},
+		{"  > not a quote", `  \> not a quote`},
+		{"- not a list\n+ or this", "\\- not a list\n\\+ or this"},
+		{"1. not a list", `1\. not a list`},
+		{"2) nor this", `2\) nor this`},
+		{"2024 was a year", "2024 was a year"},
+		{"a # in the middle", "a # in the middle"},
+		{"AT&T &amp; &#34; & more", `AT\&T \&amp; \&#34; & more`},
+	}
+	for _, c := range cases {
+		if got := escapeMarkdown(c.text); got != c.expected {
+			t.Errorf("escapeMarkdown(%q) = %q, want %q", c.text, got, c.expected)
+		}
+	}
+}
+
+func TestSingleLine(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{"one line", "one line"},
+		{"two\nlines", "two lines"},
+		{"indented  \n\t\tlines", "indented lines"},
+		{"blank\n\n\nlines", "blank lines"},
+		{"windows\r\nlines", "windows lines"},
+	}
+	for _, c := range cases {
+		if got := singleLine(c.text); got != c.expected {
+			t.Errorf("singleLine(%q) = %q, want %q", c.text, got, c.expected)
+		}
+	}
+}
+
+func TestMarkdownSingleLine(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected string
+	}{
+		// Newlines in a heading would end it, and the next line could start another block, which is not escaped once it is joined onto the line before
+		{"@section{A long\n# title}{@para{text}}", "\n# A long # title\n\ntext\n"},
+		{"@section{T}{@subsection{A @bold{long\ntitle} with @icode{some\ncode}}{}}", "\n# T\n\n## A **long title** with `some code`\n"},
+		{"@itemize{first\n- item}{second}", "\n - first - item\n - second\n"},
+		{"@enumerate{first\n\n  item}{second}", "\n 1. first item\n 2. second\n"},
+		{"@para{@link{a\n\nlink}{https://example.com}}", "\n[a link](https://example.com)\n"},
+		// Paragraphs may span lines
+		{"@para{a\nb}", "\na\nb\n"},
+		{"@para{a | b}", "\na \\| b\n"},
+	}
+	for _, c := range cases {
+		sem := mustParseMarkup(t, "@doc{"+c.src+"}")
+		if got := mustRender(t, NewMarkdownRenderer(), sem); got != c.expected {
+			t.Errorf("%q: got %q, want %q", c.src, got, c.expected)
+		}
+	}
+}
+
+func TestMarkdownMediaSingleLine(t *testing.T) {
+	defer func(old bool) { UseHTMLImageRendering = old }(UseHTMLImageRendering)
+	UseHTMLImageRendering = false
+	cases := []struct {
+		src      string
+		expected string
+	}{
+		{"@img{A @bold{long\n\ncaption}}{cat.png}", "\n![A **long caption**](cat.png)\n"},
+		{"@img{A cat}{cat.png}{alt\n\ntext}", "\n![alt text](cat.png)\n"},
+		{"@vid{A long\ncaption}{dog.mp4}", "\n[A long caption](dog.mp4)\n"},
+	}
+	for _, c := range cases {
+		sem := mustParseMarkup(t, "@doc{"+c.src+"}")
+		if got := mustRender(t, NewMarkdownRenderer(), sem); got != c.expected {
+			t.Errorf("%q: got %q, want %q", c.src, got, c.expected)
+		}
+	}
+}
+
+func TestMarkdownURLSchemes(t *testing.T) {
+	link := &LinkSemNode{obtext.CaptionedLinkSemNode{CaptionContent: &obtext.ContentBlockSemNode{}, Link: "ftp://example.com"}}
+	// URLs are written as they are by default
+	if got := mustRender(t, NewMarkdownRenderer(), link); got != "[](ftp://example.com)" {
+		t.Errorf("got %q", got)
+	}
+	r := NewMarkdownRenderer(WithMarkdownURLSchemes(DefaultURLSchemes...))
+	if got := mustRender(t, r, link); got != "[](#)" {
+		t.Errorf("got %q with the default schemes", got)
+	}
+	link.Link = "relative/path.html"
+	if got := mustRender(t, r, link); got != "[](relative/path.html)" {
+		t.Errorf("got %q for a relative link", got)
+	}
+	link.Link = "ftp://example.com"
+	if got := mustRender(t, NewMarkdownRenderer(WithMarkdownURLSchemes("ftp")), link); got != "[](ftp://example.com)" {
+		t.Errorf("got %q with ftp allowed", got)
+	}
+}
@@ -0,0 +1,319 @@
+package markup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/JoshPattman/obtext"
+)
+
+// ContentBlockType is the name that content blocks are rendered under in a Renderer, as they have no syntax type.
+// Text is rendered under obtext.TextContent.
+const ContentBlockType = "#block"
+
+// RenderFunc renders a single node by writing to the context, using it to render any of its children.
+// It should return any error from rendering the children, along with its own errors, such as a file that could not be read.
+type RenderFunc func(c *RenderContext, n obtext.SemNode) error
+
+// Renderer renders semantic trees by calling the render function registered for the syntax type of each node.
+// Render functions can be added or replaced individually, so custom objects can be supported without copying the whole renderer.
+type Renderer struct {
+	funcs map[string]RenderFunc
+	// Fallback renders any node whose syntax type has no render function, including unknown objects kept by obtext.UnknownKeep.
+	// If nil, the children of the node are rendered with nothing around them.
+	Fallback RenderFunc
+}
+
+// NewRenderer returns a renderer with no render functions, apart from content blocks, which render each of their elements in turn.
+func NewRenderer() *Renderer {
+	r := &Renderer{funcs: make(map[string]RenderFunc)}
+	r.Handle(ContentBlockType, RenderChildren)
+	return r
+}
+
+// Handle sets the render function for the given syntax type, replacing any existing one.
+// Use obtext.TextContent for text and ContentBlockType for content blocks.
+func (r *Renderer) Handle(syntaxType string, f RenderFunc) {
+	r.funcs[syntaxType] = f
+}
+
+// Clone returns a copy of the renderer, which can be changed without affecting the original.
+func (r *Renderer) Clone() *Renderer {
+	res := &Renderer{funcs: make(map[string]RenderFunc, len(r.funcs)), Fallback: r.Fallback}
+	for k, f := range r.funcs {
+		res.funcs[k] = f
+	}
+	return res
+}
+
+// Render writes the rendered semantic tree to w as it goes, so the whole output is never held in memory.
+// The output is buffered, so w does not need to be.
+// If a node fails to render, the returned error is a *RenderError, unless the render was made lenient with WithWarnings.
+func (r *Renderer) Render(w io.Writer, n obtext.SemNode, opts ...RenderOption) error {
+	return r.render(w, n, "", newRenderOptions(opts))
+}
+
+// RenderString renders the semantic tree to a string.
+func (r *Renderer) RenderString(n obtext.SemNode, opts ...RenderOption) (string, error) {
+	sb := &strings.Builder{}
+	err := r.render(sb, n, "", newRenderOptions(opts))
+	return sb.String(), err
+}
+
+func (r *Renderer) render(w io.Writer, n obtext.SemNode, indent string, opts *renderOptions) error {
+	bw := bufio.NewWriter(w)
+	c := &RenderContext{Renderer: r, Indent: indent, out: &renderOutput{w: bw, opts: opts}}
+	if err := c.Render(n); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// RenderOption is an option that changes the behaviour of Renderer.Render.
+type RenderOption func(*renderOptions)
+
+type renderOptions struct {
+	sourceMap *obtext.SourceMap
+	warnings  *[]*RenderError
+	// errorText, if set, escapes the message of a node that fails to render so that it can be written in place of the node.
+	errorText func(string) string
+}
+
+func newRenderOptions(opts []RenderOption) *renderOptions {
+	res := &renderOptions{}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// WithSourceMap gives the renderer the source map filled in by obtext.WithSourceMap, so that RenderErrors include where the failing node was parsed from.
+func WithSourceMap(m *obtext.SourceMap) RenderOption {
+	return func(o *renderOptions) {
+		o.sourceMap = m
+	}
+}
+
+// WithWarnings makes rendering lenient, so that a node that fails to render is appended to warnings instead of stopping the render.
+// Whatever the node wrote before it failed is kept, and rendering continues with the next node.
+// Errors from writing the output still stop the render.
+func WithWarnings(warnings *[]*RenderError) RenderOption {
+	return func(o *renderOptions) {
+		o.warnings = warnings
+	}
+}
+
+// inlineErrors returns the options with each node that fails to render replaced by its error message, escaped with escape,
+// for the deprecated renders that cannot return an error. Warnings are still collected if the options ask for them.
+func inlineErrors(opts []RenderOption, escape func(string) string) []RenderOption {
+	var discarded []*RenderError
+	inline := func(o *renderOptions) {
+		o.errorText = escape
+	}
+	return append([]RenderOption{WithWarnings(&discarded), inline}, opts...)
+}
+
+// RenderError is returned when a node fails to render, such as embedded code whose file cannot be read.
+type RenderError struct {
+	// Node is the node that failed to render.
+	Node obtext.SemNode
+	// Pos is the position that the node was parsed from, which is only valid if a source map was given with WithSourceMap.
+	Pos obtext.Position
+	// Path is the path of objects from the root to the node, which is only set if a source map was given with WithSourceMap.
+	Path obtext.ObjectPath
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+// The position and path are only included if they are known.
+func (e *RenderError) Error() string {
+	name := renderType(e.Node)
+	if name == e.Node.SyntaxType() {
+		name = "@" + name
+	}
+	msg := fmt.Sprintf("failed to render %s: %s", name, e.Err)
+	if e.Pos.IsValid() {
+		msg = fmt.Sprintf("%s: %s: %s", e.Pos, e.Path, msg)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// RenderContext is passed to each RenderFunc, to write the output and render children with the same renderer and settings.
+// It is an io.Writer, so can be used with fmt.Fprintf. If a write fails, all later writes are skipped and the error is returned by Render.
+type RenderContext struct {
+	Renderer *Renderer
+	// Indent is the indentation to put before each line, which is used by the html renderer.
+	Indent string
+	// singleLine is set while rendering something that must stay on one line, which is used by the markdown renderer for headings and list items.
+	singleLine bool
+	out        *renderOutput
+}
+
+// renderOutput is the writer shared by a context and all of the contexts derived from it, along with the first error from writing to it.
+type renderOutput struct {
+	w    *bufio.Writer
+	err  error
+	opts *renderOptions
+}
+
+// Write implements the io.Writer interface.
+func (c *RenderContext) Write(p []byte) (int, error) {
+	if c.out.err != nil {
+		return 0, c.out.err
+	}
+	n, err := c.out.w.Write(p)
+	c.out.err = err
+	return n, err
+}
+
+// WriteString implements the io.StringWriter interface.
+func (c *RenderContext) WriteString(s string) (int, error) {
+	if c.out.err != nil {
+		return 0, c.out.err
+	}
+	n, err := c.out.w.WriteString(s)
+	c.out.err = err
+	return n, err
+}
+
+// Render renders a node using the render function for its syntax type, or the fallback if there is none.
+func (c *RenderContext) Render(n obtext.SemNode) error {
+	if c.out.err != nil {
+		return c.out.err
+	}
+	var err error
+	if f, ok := c.Renderer.funcs[renderType(n)]; ok {
+		err = f(c, n)
+	} else if c.Renderer.Fallback != nil {
+		err = c.Renderer.Fallback(c, n)
+	} else {
+		err = RenderChildren(c, n)
+	}
+	if err != nil && err != c.out.err {
+		var renderErr *RenderError
+		if errors.As(err, &renderErr) {
+			// A child failed, and has already been wrapped
+			return err
+		}
+		renderErr = &RenderError{Node: n, Pos: c.out.opts.sourceMap.Pos(n), Path: c.out.opts.sourceMap.Path(n), Err: err}
+		if c.out.opts.warnings == nil {
+			return renderErr
+		}
+		*c.out.opts.warnings = append(*c.out.opts.warnings, renderErr)
+		if c.out.opts.errorText != nil {
+			c.WriteString(c.out.opts.errorText(renderErr.Error()))
+		}
+		return c.out.err
+	}
+	if err != nil {
+		return err
+	}
+	return c.out.err
+}
+
+// Print writes each of the parts in turn, which may be strings or nodes to render, stopping at the first error.
+func (c *RenderContext) Print(parts ...any) error {
+	for _, p := range parts {
+		switch p := p.(type) {
+		case string:
+			c.WriteString(p)
+		case obtext.SemNode:
+			if err := c.Render(p); err != nil {
+				return err
+			}
+		default:
+			panic(fmt.Sprintf("cannot print %T", p))
+		}
+	}
+	return c.out.err
+}
+
+// RenderString renders a node to a string with the same renderer, rather than writing it, such as for use in an attribute.
+func (c *RenderContext) RenderString(n obtext.SemNode) (string, error) {
+	return c.RenderStringWith(c.Renderer, n)
+}
+
+// RenderStringWith is like RenderString, but renders the node with a different renderer, keeping the same options.
+// This can be used to render html inside of markdown, for example.
+func (c *RenderContext) RenderStringWith(r *Renderer, n obtext.SemNode) (string, error) {
+	sb := &strings.Builder{}
+	err := r.render(sb, n, c.Indent, c.out.opts)
+	return sb.String(), err
+}
+
+// WithIndent returns a copy of the context with a different indent, which writes to the same output.
+func (c *RenderContext) WithIndent(indent string) *RenderContext {
+	res := *c
+	res.Indent = indent
+	return &res
+}
+
+// withSingleLine returns a copy of the context for rendering something that must stay on one line, which writes to the same output.
+func (c *RenderContext) withSingleLine() *RenderContext {
+	res := *c
+	res.singleLine = true
+	return &res
+}
+
+// renderType returns the name that a node is rendered under.
+func renderType(n obtext.SemNode) string {
+	switch n.(type) {
+	case *obtext.TextSemNode:
+		return obtext.TextContent
+	case *obtext.ContentBlockSemNode:
+		return ContentBlockType
+	}
+	return n.SyntaxType()
+}
+
+// RenderChildren is a RenderFunc that renders each child of the node in turn, with nothing around them.
+func RenderChildren(c *RenderContext, n obtext.SemNode) error {
+	for _, child := range n.Children() {
+		if err := c.Render(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PanicFallback is a fallback RenderFunc that panics, for renderers that must support every node in the tree.
+func PanicFallback(c *RenderContext, n obtext.SemNode) error {
+	panic(fmt.Sprintf("node type %T (@%s) was not included in renderer", n, n.SyntaxType()))
+}
+
+// highlightFallback is the default fallback of the built in renderers, which highlights the node so it is easy to spot when previewing.
+// The syntax type is escaped, as nodes made by passes or other code may have any syntax type.
+func highlightFallback(c *RenderContext, n obtext.SemNode) error {
+	c.WriteString(fmt.Sprintf("<mark title=\"unknown object @%s\">", html.EscapeString(n.SyntaxType())))
+	if err := RenderChildren(c.WithIndent(""), n); err != nil {
+		return err
+	}
+	return c.Print("</mark>")
+}
+
+// HandleNode sets the render function for the syntax type of T, which must be a pointer to a struct, such as *BoldSemNode.
+// The function is only called for nodes of type T, and any other node with the same syntax type is given to the fallback.
+func HandleNode[T obtext.SemNode](r *Renderer, f func(c *RenderContext, n T) error) {
+	var zero T
+	syntaxType := reflect.New(reflect.TypeOf(zero).Elem()).Interface().(T).SyntaxType()
+	r.Handle(syntaxType, func(c *RenderContext, n obtext.SemNode) error {
+		if t, ok := n.(T); ok {
+			return f(c, t)
+		}
+		if c.Renderer.Fallback != nil {
+			return c.Renderer.Fallback(c, n)
+		}
+		return RenderChildren(c, n)
+	})
+}
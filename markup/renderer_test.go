@@ -0,0 +1,299 @@
+package markup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JoshPattman/obtext"
+)
+
+// mustParseMarkup parses the source with the markup registry, keeping unknown objects.
+func mustParseMarkup(t testing.TB, src string) obtext.SemNode {
+	t.Helper()
+	sem, err := NewRegistry().Parse(mustParseSyn(t, src), obtext.WithUnknownObjects(obtext.UnknownKeep))
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", src, err)
+	}
+	return sem
+}
+
+// mustRender renders the tree with the renderer, failing the test if it returns an error.
+func mustRender(t testing.TB, r *Renderer, n obtext.SemNode) string {
+	t.Helper()
+	out, err := r.RenderString(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRendererHandle(t *testing.T) {
+	sem := mustParseMarkup(t, "@doc{@para{a @bold{b}}}")
+	r := NewRenderer()
+	// Without any render functions, only the text is rendered
+	r.Handle(obtext.TextContent, func(c *RenderContext, n obtext.SemNode) error {
+		return c.Print(n.(*obtext.TextSemNode).Text)
+	})
+	if got := mustRender(t, r, sem); got != "a b" {
+		t.Errorf("got %q", got)
+	}
+	HandleNode(r, func(c *RenderContext, n *BoldSemNode) error {
+		return c.Print("[", n.Content, "]")
+	})
+	if got := mustRender(t, r, sem); got != "a [b]" {
+		t.Errorf("got %q", got)
+	}
+	// Handle replaces the existing function
+	r.Handle("bold", func(c *RenderContext, n obtext.SemNode) error {
+		return c.Print("B")
+	})
+	if got := mustRender(t, r, sem); got != "a B" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRendererClone(t *testing.T) {
+	sem := mustParseMarkup(t, "@doc{@para{a @bold{b}}}")
+	r := NewMarkdownRenderer()
+	clone := r.Clone()
+	HandleNode(clone, func(c *RenderContext, n *BoldSemNode) error {
+		return c.Print("__", n.Content, "__")
+	})
+	if got := mustRender(t, clone, sem); got != "\na __b__\n" {
+		t.Errorf("got %q from the clone", got)
+	}
+	if got := mustRender(t, r, sem); got != "\na **b**\n" {
+		t.Errorf("changing the clone changed the original, which renders %q", got)
+	}
+}
+
+func TestRendererFallback(t *testing.T) {
+	sem := mustParseMarkup(t, "@doc{@para{a @foo{b}}}")
+	if got := mustRender(t, NewMarkdownRenderer(), sem); got != "\na <mark title=\"unknown object @foo\">b</mark>\n" {
+		t.Errorf("got %q with the default fallback", got)
+	}
+	r := NewMarkdownRenderer()
+	r.Fallback = nil
+	if got := mustRender(t, r, sem); got != "\na b\n" {
+		t.Errorf("got %q without a fallback", got)
+	}
+	r.Fallback = func(c *RenderContext, n obtext.SemNode) error {
+		return c.Print("?" + n.SyntaxType())
+	}
+	if got := mustRender(t, r, sem); got != "\na ?foo\n" {
+		t.Errorf("got %q with a custom fallback", got)
+	}
+	r.Fallback = PanicFallback
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected PanicFallback to panic")
+		}
+	}()
+	r.RenderString(sem)
+}
+
+func TestHandleNodeOtherTypes(t *testing.T) {
+	// A node with the same syntax type as a handled node, but a different type, is given to the fallback
+	r := NewRenderer()
+	r.Fallback = func(c *RenderContext, n obtext.SemNode) error {
+		return c.Print("fallback")
+	}
+	HandleNode(r, func(c *RenderContext, n *BoldSemNode) error {
+		return c.Print("bold")
+	})
+	unknown := &obtext.UnknownSemNode{Type: "bold"}
+	if got := mustRender(t, r, unknown); got != "fallback" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRenderCompatibility(t *testing.T) {
+	// RenderHTML and RenderMarkdown keep their original signatures, writing the error of a node that fails in its place
+	sem := mustParseMarkup(t, "@doc{@para{a} @code{go}{does-not-exist<1>.go} @para{b}}")
+	html := RenderHTML(sem, "")
+	md := RenderMarkdown(sem)
+	if !strings.Contains(html, "<p>a</p>") || !strings.Contains(html, "<p>b</p>") || !strings.Contains(html, "failed to render @code: open does-not-exist&lt;1&gt;.go") {
+		t.Errorf("got html %q", html)
+	}
+	if !strings.HasPrefix(md, "\na\n") || !strings.HasSuffix(md, "\nb\n") || !strings.Contains(md, "failed to render @code: open does-not-exist\\<1>.go") {
+		t.Errorf("got markdown %q", md)
+	}
+	if _, err := RenderHTMLErr(sem, ""); err == nil {
+		t.Errorf("expected an error from RenderHTMLErr")
+	}
+	if _, err := RenderMarkdownErr(sem); err == nil {
+		t.Errorf("expected an error from RenderMarkdownErr")
+	}
+	// Warnings are still collected if asked for
+	var warnings []*RenderError
+	RenderMarkdown(sem, WithWarnings(&warnings))
+	if len(warnings) != 1 {
+		t.Errorf("got %d warnings, want 1", len(warnings))
+	}
+}
+
+// largeDocument generates a document with the given number of sections, each with a few paragraphs, a list and a link.
+func largeDocument(tb testing.TB, sections int) obtext.SemNode {
+	tb.Helper()
+	sb := &strings.Builder{}
+	sb.WriteString("@doc{")
+	for i := 0; i < sections; i++ {
+		fmt.Fprintf(sb, "@section{Section %d}{", i)
+		sb.WriteString("@para{Some @bold{bold} and @italic{italic} text, with @icode{code} and a @link{https://example.com}{link}.}")
+		sb.WriteString("@itemize{One}{Two @bold{three}}{Four}")
+		sb.WriteString("@subsection{More}{@para{Another paragraph of text, which goes on for a little while.}}")
+		sb.WriteString("}")
+	}
+	sb.WriteString("}")
+	return mustParseMarkup(tb, sb.String())
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestRenderStreaming(t *testing.T) {
+	sem := largeDocument(t, 100)
+	for name, r := range map[string]*Renderer{"html": NewHTMLRenderer(), "markdown": NewMarkdownRenderer()} {
+		expected := mustRender(t, r, sem)
+		buf := &bytes.Buffer{}
+		if err := r.Render(buf, sem); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected {
+			t.Errorf("%s: writing the output gave a different result to rendering a string", name)
+		}
+		if err := r.Render(failingWriter{}, sem); err == nil || err.Error() != "write failed" {
+			t.Errorf("%s: got %v, want the error from the writer", name, err)
+		}
+	}
+}
+
+func TestRenderCodeErrors(t *testing.T) {
+	// Files that cannot be read are errors, rather than being written into the output
+	sem := mustParseMarkup(t, "@doc{@para{a} @code{go}{does-not-exist.go}}")
+	out, err := NewMarkdownRenderer().RenderString(sem)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want an error for the missing file", err)
+	}
+	if strings.Contains(out, "does-not-exist.go") {
+		t.Errorf("the error was written into the output: %q", out)
+	}
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sem = mustParseMarkup(t, "@doc{@code{go}{"+path+"}}")
+	if got := mustRender(t, NewMarkdownRenderer(), sem); got != "```go\npackage main\n\n```\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func BenchmarkRenderString(b *testing.B) {
+	sem := largeDocument(b, 2000)
+	r := NewMarkdownRenderer()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.RenderString(sem); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	sem := largeDocument(b, 2000)
+	r := NewMarkdownRenderer()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.Render(io.Discard, sem); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderHTML(b *testing.B) {
+	sem := largeDocument(b, 2000)
+	r := NewHTMLRenderer()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.Render(io.Discard, sem); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	src := "@doc{\n\t@para{a}\n\t@code{go}{does-not-exist.go}\n}"
+	m := &obtext.SourceMap{}
+	sem, err := NewRegistry().Parse(mustParseSyn(t, src), obtext.WithSourceMap(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := obtext.FindAll[*EmbeddedCodeSemNode](sem)[0]
+	_, err = NewMarkdownRenderer().RenderString(sem, WithSourceMap(m))
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) {
+		t.Fatalf("got %T: %v, want a *RenderError", err, err)
+	}
+	if renderErr.Node != code || renderErr.Pos.String() != "3:2" || renderErr.Path.String() != "doc > code[1]" {
+		t.Errorf("got %+v", renderErr)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the error does not wrap the error from opening the file")
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "3:2: doc > code[1]: failed to render @code: ") {
+		t.Errorf("got message %q", msg)
+	}
+	// Without a source map the position is not known
+	_, err = NewMarkdownRenderer().RenderString(sem)
+	if !errors.As(err, &renderErr) || renderErr.Pos.IsValid() || !strings.HasPrefix(err.Error(), "failed to render @code: ") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestRenderErrorWrappedOnce(t *testing.T) {
+	// An error from a nested node is reported for that node, not for the objects around it
+	r := NewMarkdownRenderer()
+	failed := errors.New("failed")
+	HandleNode(r, func(c *RenderContext, n *BoldSemNode) error {
+		return failed
+	})
+	sem := mustParseMarkup(t, "@doc{@section{Title}{@para{a @bold{b}}}}")
+	_, err := r.RenderString(sem)
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) || renderErr.Node.SyntaxType() != "bold" || renderErr.Err != failed {
+		t.Errorf("got %v", err)
+	}
+	if err.Error() != "failed to render @bold: failed" {
+		t.Errorf("got message %q", err)
+	}
+}
+
+func TestRenderWarnings(t *testing.T) {
+	sem := mustParseMarkup(t, "@doc{@para{a} @code{go}{missing1.go} @para{b} @code{go}{missing2.go} @para{c}}")
+	var warnings []*RenderError
+	out, err := NewMarkdownRenderer().RenderString(sem, WithWarnings(&warnings))
+	if err != nil {
+		t.Fatalf("a lenient render failed: %v", err)
+	}
+	if out != "\na\n\nb\n\nc\n" {
+		t.Errorf("the rest of the document was not rendered: %q", out)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0].Error(), "missing1.go") || !strings.Contains(warnings[1].Error(), "missing2.go") {
+		t.Errorf("got warnings %v", warnings)
+	}
+	// Errors from writing the output still stop the render
+	warnings = nil
+	if err := NewMarkdownRenderer().Render(failingWriter{}, largeDocument(t, 10), WithWarnings(&warnings)); err == nil || len(warnings) != 0 {
+		t.Errorf("got %v with warnings %v", err, warnings)
+	}
+}
@@ -20,6 +20,36 @@ var Semantics = []obtext.SemNode{
 	&LinkSemNode{},
 }
 
+// NewRegistry returns a new registry containing all of the nodes in Semantics.
+// It can be extended with custom nodes and aliases without affecting other registries.
+// Build a registry once and reuse it to parse many documents.
+func NewRegistry() *obtext.Registry {
+	reg := obtext.MustNewRegistry()
+	for _, n := range Semantics {
+		if err := reg.Register(n); err != nil {
+			panic(err)
+		}
+	}
+	return reg
+}
+
+// The content categories used by the content models of the markup nodes.
+const (
+	// BlockCategory contains nodes that are rendered on their own line, such as sections and paragraphs.
+	BlockCategory = "block"
+	// InlineCategory contains nodes that are rendered within a line of text, such as bold text and links.
+	InlineCategory = "inline"
+	// ListCategory contains lists, which may be used inside paragraphs and other lists as well as wherever blocks can be.
+	ListCategory = "list"
+)
+
+var (
+	blockContent  = obtext.ArgContentModel{Allow: []string{BlockCategory}}
+	inlineContent = obtext.ArgContentModel{Allow: []string{InlineCategory, obtext.TextContent}}
+	textContent   = obtext.ArgContentModel{Allow: []string{obtext.TextContent}}
+	itemContent   = obtext.ArgContentModel{Allow: []string{InlineCategory, ListCategory, obtext.TextContent}}
+)
+
 // DocSemNode is a semantic node that represents a document.
 type DocSemNode struct {
 	obtext.SingleArgSemNode
@@ -30,6 +60,27 @@ func (d *DocSemNode) SyntaxType() string {
 	return "doc"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (d *DocSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Parents: []string{obtext.RootParent},
+		Args:    []obtext.ArgContentModel{blockContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (d *DocSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "The root of a document, containing all of its sections and paragraphs.",
+		Args: []obtext.ArgSchema{
+			{Name: "content", Description: "The blocks of the document."},
+		},
+		Examples: []string{
+			"@doc{@section{Title}{@para{Hello, world!}}}",
+		},
+	}
+}
+
 // SectionSemNode is a semantic node that represents a section (level 1 heading usually).
 type SectionSemNode struct {
 	obtext.DualArgSemNode
@@ -40,6 +91,28 @@ func (h *SectionSemNode) SyntaxType() string {
 	return "section"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (h *SectionSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{BlockCategory},
+		Args:       []obtext.ArgContentModel{inlineContent, blockContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (h *SectionSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "A top level section with a heading.",
+		Args: []obtext.ArgSchema{
+			{Name: "title", Description: "The heading of the section."},
+			{Name: "content", Description: "The blocks of the section."},
+		},
+		Examples: []string{
+			"@section{Introduction}{@para{Some text.}}",
+		},
+	}
+}
+
 // SubSectionSemNode is a semantic node that represents a subsection (level 2 heading).
 type SubSectionSemNode struct {
 	obtext.DualArgSemNode
@@ -50,6 +123,29 @@ func (h *SubSectionSemNode) SyntaxType() string {
 	return "subsection"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (h *SubSectionSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{BlockCategory},
+		Parents:    []string{"section"},
+		Args:       []obtext.ArgContentModel{inlineContent, blockContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (h *SubSectionSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "A section inside of a section, with a smaller heading.",
+		Args: []obtext.ArgSchema{
+			{Name: "title", Description: "The heading of the subsection."},
+			{Name: "content", Description: "The blocks of the subsection."},
+		},
+		Examples: []string{
+			"@subsection{Details}{@para{Some more text.}}",
+		},
+	}
+}
+
 // PSemNode is a semantic node that represents a paragraph.
 type PSemNode struct {
 	obtext.SingleArgSemNode
@@ -60,6 +156,27 @@ func (p *PSemNode) SyntaxType() string {
 	return "para"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (p *PSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{BlockCategory},
+		Args:       []obtext.ArgContentModel{itemContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (p *PSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "A paragraph of text, which may also contain lists.",
+		Args: []obtext.ArgSchema{
+			{Name: "content", Description: "The text of the paragraph."},
+		},
+		Examples: []string{
+			"@para{Some @bold{important} text.}",
+		},
+	}
+}
+
 // BoldSemNode is a semantic node that represents bold text.
 type BoldSemNode struct {
 	obtext.SingleArgSemNode
@@ -70,6 +187,27 @@ func (b *BoldSemNode) SyntaxType() string {
 	return "bold"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (b *BoldSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{InlineCategory},
+		Args:       []obtext.ArgContentModel{inlineContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (b *BoldSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "Bold text.",
+		Args: []obtext.ArgSchema{
+			{Name: "text", Description: "The text to make bold."},
+		},
+		Examples: []string{
+			"@bold{important}",
+		},
+	}
+}
+
 // ItalicSemNode is a semantic node that represents italic text.
 type ItalicSemNode struct {
 	obtext.SingleArgSemNode
@@ -80,9 +218,31 @@ func (i *ItalicSemNode) SyntaxType() string {
 	return "italic"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (i *ItalicSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{InlineCategory},
+		Args:       []obtext.ArgContentModel{inlineContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (i *ItalicSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "Italic text.",
+		Args: []obtext.ArgSchema{
+			{Name: "text", Description: "The text to make italic."},
+		},
+		Examples: []string{
+			"@italic{emphasised}",
+		},
+	}
+}
+
 // ImageSemNode is a semantic node that represents an image.
+// It has an optional third arg for alternative text, which is used instead of the caption if given.
 type ImageSemNode struct {
-	obtext.CaptionedLinkSemNode
+	obtext.CaptionedMediaSemNode
 }
 
 // SyntaxType implements the SemNode interface.
@@ -90,9 +250,34 @@ func (i *ImageSemNode) SyntaxType() string {
 	return "img"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (i *ImageSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{BlockCategory},
+		Args:       []obtext.ArgContentModel{inlineContent, textContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (i *ImageSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "An image with a caption.",
+		Args: []obtext.ArgSchema{
+			{Name: "caption", Description: "The caption of the image."},
+			{Name: "url", Description: "The location of the image."},
+			{Name: "alt", Description: "Alternative text, used instead of the caption for screen readers."},
+		},
+		Examples: []string{
+			"@img{A cat}{cat.png}",
+			"@img{A cat}{cat.png}{A ginger cat asleep on a sofa}",
+		},
+	}
+}
+
 // VideoSemNode is a semantic node that represents a video.
+// It has an optional third arg for alternative text, like ImageSemNode.
 type VideoSemNode struct {
-	obtext.CaptionedLinkSemNode
+	obtext.CaptionedMediaSemNode
 }
 
 // SyntaxType implements the SemNode interface.
@@ -100,6 +285,29 @@ func (v *VideoSemNode) SyntaxType() string {
 	return "vid"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (v *VideoSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{BlockCategory},
+		Args:       []obtext.ArgContentModel{inlineContent, textContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (v *VideoSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "A video with a caption.",
+		Args: []obtext.ArgSchema{
+			{Name: "caption", Description: "The caption of the video."},
+			{Name: "url", Description: "The location of the video."},
+			{Name: "alt", Description: "Alternative text, used instead of the caption for screen readers."},
+		},
+		Examples: []string{
+			"@vid{A demo}{demo.mp4}",
+		},
+	}
+}
+
 // EmbeddedCodeSemNode is a semantic node that represents embedded code.
 type EmbeddedCodeSemNode struct {
 	obtext.DualStringSemNode
@@ -110,6 +318,28 @@ func (c *EmbeddedCodeSemNode) SyntaxType() string {
 	return "code"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (c *EmbeddedCodeSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{BlockCategory},
+		Args:       []obtext.ArgContentModel{textContent, textContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (c *EmbeddedCodeSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "A block of code that is read from a file when rendering.",
+		Args: []obtext.ArgSchema{
+			{Name: "language", Description: "The language of the code, used for syntax highlighting."},
+			{Name: "path", Description: "The path of the file containing the code."},
+		},
+		Examples: []string{
+			"@code{go}{main.go}",
+		},
+	}
+}
+
 // InlineCodeSemNode is a semantic node that represents inline code.
 type InlineCodeSemNode struct {
 	obtext.SingleArgSemNode
@@ -119,6 +349,27 @@ func (i *InlineCodeSemNode) SyntaxType() string {
 	return "icode"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (i *InlineCodeSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{InlineCategory},
+		Args:       []obtext.ArgContentModel{inlineContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (i *InlineCodeSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "A short piece of code within a line of text.",
+		Args: []obtext.ArgSchema{
+			{Name: "code", Description: "The code."},
+		},
+		Examples: []string{
+			"@icode{fmt.Println}",
+		},
+	}
+}
+
 // UlSemNode is a semantic node that represents an unordered list.
 type UlSemNode struct {
 	obtext.ListArgSemNode
@@ -129,6 +380,27 @@ func (u *UlSemNode) SyntaxType() string {
 	return "itemize"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (u *UlSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{BlockCategory, ListCategory},
+		Args:       []obtext.ArgContentModel{itemContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (u *UlSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "An unordered (bullet point) list, with one arg per item.",
+		Args: []obtext.ArgSchema{
+			{Name: "items", Description: "The items of the list."},
+		},
+		Examples: []string{
+			"@itemize{First}{Second}{Third}",
+		},
+	}
+}
+
 // OlSemNode is a semantic node that represents an ordered list.
 type OlSemNode struct {
 	obtext.ListArgSemNode
@@ -139,6 +411,27 @@ func (o *OlSemNode) SyntaxType() string {
 	return "enumerate"
 }
 
+// ContentModel implements the ContentModelSemNode interface.
+func (o *OlSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{BlockCategory, ListCategory},
+		Args:       []obtext.ArgContentModel{itemContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (o *OlSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "An ordered (numbered) list, with one arg per item.",
+		Args: []obtext.ArgSchema{
+			{Name: "items", Description: "The items of the list."},
+		},
+		Examples: []string{
+			"@enumerate{First}{Second}{Third}",
+		},
+	}
+}
+
 // LinkSemNode is a semantic node that represents a link.
 type LinkSemNode struct {
 	obtext.CaptionedLinkSemNode
@@ -148,3 +441,25 @@ type LinkSemNode struct {
 func (l *LinkSemNode) SyntaxType() string {
 	return "link"
 }
+
+// ContentModel implements the ContentModelSemNode interface.
+func (l *LinkSemNode) ContentModel() obtext.ContentModel {
+	return obtext.ContentModel{
+		Categories: []string{InlineCategory},
+		Args:       []obtext.ArgContentModel{inlineContent, textContent},
+	}
+}
+
+// Describe implements the DescribedSemNode interface.
+func (l *LinkSemNode) Describe() obtext.NodeDoc {
+	return obtext.NodeDoc{
+		Summary: "A link to another page.",
+		Args: []obtext.ArgSchema{
+			{Name: "caption", Description: "The text of the link."},
+			{Name: "url", Description: "The location that the link points to."},
+		},
+		Examples: []string{
+			"@link{the obtext repo}{https://github.com/JoshPattman/obtext}",
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package markup
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JoshPattman/obtext"
+)
+
+func TestNewRegistryMatchesSemantics(t *testing.T) {
+	reg := NewRegistry()
+	if len(reg.Names()) != len(Semantics) {
+		t.Errorf("the registry has %d nodes, but there are %d semantics", len(reg.Names()), len(Semantics))
+	}
+	for _, n := range Semantics {
+		a, ok := reg.New(n.SyntaxType())
+		if !ok {
+			t.Errorf("@%s is not registered", n.SyntaxType())
+			continue
+		}
+		b, _ := reg.New(n.SyntaxType())
+		if reflect.TypeOf(a) != reflect.TypeOf(n) {
+			t.Errorf("@%s constructs a %T, want a %T", n.SyntaxType(), a, n)
+		}
+		if a == b || a == n {
+			t.Errorf("@%s does not construct a new node each time", n.SyntaxType())
+		}
+	}
+}
+
+func TestRegistryAndParseSemAgree(t *testing.T) {
+	syn := mustParseSyn(t, "@doc{@section{Title}{@para{Some @bold{text} and @link{https://example.com}{a link}.}}}")
+	fromReg, err := NewRegistry().Parse(syn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSem, err := obtext.ParseSem(syn, Semantics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromReg, fromSem) {
+		t.Errorf("parsing with the registry and with Semantics gave different trees")
+	}
+}
+
+func mustParseSyn(t testing.TB, src string) *obtext.ObjectSynNode {
+	t.Helper()
+	syn, err := obtext.ParseSynString(src)
+	if err != nil {
+		t.Fatalf("failed to parse syntax of %q: %v", src, err)
+	}
+	return syn
+}
+
+func TestImageAlt(t *testing.T) {
+	sem, err := NewRegistry().Parse(mustParseSyn(t, "@doc{@img{A cat}{cat.png}{A cat sitting on a mat} @vid{A dog}{dog.mp4}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := obtext.FindAll[*ImageSemNode](sem)
+	vid := obtext.FindAll[*VideoSemNode](sem)
+	if len(img) != 1 || len(vid) != 1 {
+		t.Fatalf("got %d images and %d videos", len(img), len(vid))
+	}
+	if img[0].Link != "cat.png" || img[0].Alt != "A cat sitting on a mat" {
+		t.Errorf("got image %+v", img[0])
+	}
+	if vid[0].Link != "dog.mp4" || vid[0].Alt != "" {
+		t.Errorf("got video %+v", vid[0])
+	}
+}
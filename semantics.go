@@ -41,6 +41,9 @@ func (t *TextSemNode) Children() []SemNode {
 // It should not be passed to ParseSem as one of the semantics.
 type ContentBlockSemNode struct {
 	Elements []SemNode
+	// CastValue is the value of this block after casting it to the type declared by the `obt` tags of the node it is an argument of,
+	// for example an int or a time.Time. It is nil if the argument has no declared type, or is content.
+	CastValue any
 }
 
 // ParseArgs implements the SemNode interface.
@@ -57,3 +60,35 @@ func (c *ContentBlockSemNode) SyntaxType() string {
 func (c *ContentBlockSemNode) Children() []SemNode {
 	return c.Elements
 }
+
+// UnknownSemNode is a special node that is used to keep an object whose type was not defined in the semantics,
+// when parsing with WithUnknownObjects(UnknownKeep).
+// It should not be passed to ParseSem as one of the semantics.
+type UnknownSemNode struct {
+	// Type is the syntax type of the unknown object.
+	Type string
+	// Args are the parsed args of the unknown object.
+	Args []*ContentBlockSemNode
+	// Pos is the position of the unknown object in the source.
+	Pos Position
+}
+
+// SyntaxType implements the SemNode interface.
+func (u *UnknownSemNode) SyntaxType() string {
+	return u.Type
+}
+
+// ParseArgs implements the SemNode interface.
+func (u *UnknownSemNode) ParseArgs(args []*ContentBlockSemNode) error {
+	u.Args = args
+	return nil
+}
+
+// Children implements the SemNode interface.
+func (u *UnknownSemNode) Children() []SemNode {
+	res := make([]SemNode, len(u.Args))
+	for i, a := range u.Args {
+		res[i] = a
+	}
+	return res
+}
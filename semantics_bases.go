@@ -1,133 +1,183 @@
 package obtext
 
-import (
-	"fmt"
-)
+// The base nodes in this file declare their arguments with `obt` tags, see ParseTaggedArgs.
+
+// checkArgCount returns an ArgCountError if the number of args is not between min and max (inclusive).
+// If max is -1, there is no maximum.
+func checkArgCount(args []*ContentBlockSemNode, min, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		return &ArgCountError{Min: min, Max: max, Got: len(args)}
+	}
+	return nil
+}
+
+// textArg returns the text of the i-th arg, or an ArgTypeError if it is not exactly one piece of text.
+func textArg(args []*ContentBlockSemNode, i int) (string, error) {
+	if len(args[i].Elements) == 1 {
+		if text, ok := args[i].Elements[0].(*TextSemNode); ok {
+			return text.Text, nil
+		}
+	}
+	return "", &ArgTypeError{Arg: i, Expected: "text", Got: describeContent(args[i])}
+}
 
 // SingleArgSemNode is a semantic node that has exactly 1 child, which is a content block.
 // It only partially implements the SemNode interface, as it does not implement SyntaxType.
 type SingleArgSemNode struct {
-	Content *ContentBlockSemNode
+	Content *ContentBlockSemNode `obt:"0,content"`
 }
 
 // ParseArgs implements the SemNode interface.
 func (d *SingleArgSemNode) ParseArgs(args []*ContentBlockSemNode) error {
-	if len(args) != 1 {
-		return fmt.Errorf("document must have exactly 1 child")
-	}
-	d.Content = args[0]
-	return nil
+	return ParseTaggedArgs(d, args)
 }
 
 // Children implements the SemNode interface.
 func (d *SingleArgSemNode) Children() []SemNode {
-	return []SemNode{d.Content}
+	return TaggedChildren(d)
+}
+
+// SetChild implements the MutableSemNode interface.
+func (d *SingleArgSemNode) SetChild(i int, n SemNode) error {
+	return TaggedSetChild(d, i, n)
+}
+
+// Unparse implements the UnparsableSemNode interface.
+func (d *SingleArgSemNode) Unparse() ([]*ContentBlockSemNode, error) {
+	return TaggedUnparse(d)
 }
 
 // CaptionedSemNode is a semantic node that has exactly 2 children, the first of which is a content block representing a caption,
 // and the second which is a string representing a URL.
 // It only partially implements the SemNode interface, as it does not implement SyntaxType.
 type CaptionedLinkSemNode struct {
-	CaptionContent *ContentBlockSemNode
-	Link           string
+	CaptionContent *ContentBlockSemNode `obt:"0,content"`
+	Link           string               `obt:"1,text"`
 }
 
 // ParseArgs implements the SemNode interface.
 func (c *CaptionedLinkSemNode) ParseArgs(args []*ContentBlockSemNode) error {
-	if len(args) != 2 {
-		return fmt.Errorf("link must have exactly 2 children")
-	}
-	if len(args[1].Elements) != 1 {
-		return fmt.Errorf("link arg1 must have exactly 1 child")
-	}
-	text, ok := args[1].Elements[0].(*TextSemNode)
-	if !ok {
-		return fmt.Errorf("link arg1 must be text")
-	}
-	c.Link = text.Text
-	c.CaptionContent = args[0]
-	return nil
+	return ParseTaggedArgs(c, args)
 }
 
 // Children implements the SemNode interface.
 func (c *CaptionedLinkSemNode) Children() []SemNode {
-	return []SemNode{c.CaptionContent}
+	return TaggedChildren(c)
+}
+
+// SetChild implements the MutableSemNode interface.
+func (c *CaptionedLinkSemNode) SetChild(i int, n SemNode) error {
+	return TaggedSetChild(c, i, n)
+}
+
+// Unparse implements the UnparsableSemNode interface.
+func (c *CaptionedLinkSemNode) Unparse() ([]*ContentBlockSemNode, error) {
+	return TaggedUnparse(c)
 }
 
 // DualStringSemNode is a semantic node that has exactly 2 children, both of which are strings.
 // It only partially implements the SemNode interface, as it does not implement SyntaxType.
 type DualStringSemNode struct {
-	Arg1 string
-	Arg2 string
+	Arg1 string `obt:"0,text"`
+	Arg2 string `obt:"1,text"`
 }
 
 // ParseArgs implements the SemNode interface.
 func (d *DualStringSemNode) ParseArgs(args []*ContentBlockSemNode) error {
-	if len(args) != 2 {
-		return fmt.Errorf("dual string must have exactly 2 children")
-	}
-	if len(args[0].Elements) != 1 {
-		return fmt.Errorf("dual string arg1 must have exactly 1 child")
-	}
-	if len(args[1].Elements) != 1 {
-		return fmt.Errorf("dual string arg2 must have exactly 1 child")
-	}
-	arg1, ok := args[0].Elements[0].(*TextSemNode)
-	if !ok {
-		return fmt.Errorf("dual string arg1 must be text")
-	}
-	arg2, ok := args[1].Elements[0].(*TextSemNode)
-	if !ok {
-		return fmt.Errorf("dual string arg2 must be text")
-	}
-	d.Arg1 = arg1.Text
-	d.Arg2 = arg2.Text
-	return nil
+	return ParseTaggedArgs(d, args)
 }
 
 // Children implements the SemNode interface.
 func (d *DualStringSemNode) Children() []SemNode {
-	return []SemNode{}
+	return TaggedChildren(d)
+}
+
+// SetChild implements the MutableSemNode interface.
+func (d *DualStringSemNode) SetChild(i int, n SemNode) error {
+	return TaggedSetChild(d, i, n)
+}
+
+// Unparse implements the UnparsableSemNode interface.
+func (d *DualStringSemNode) Unparse() ([]*ContentBlockSemNode, error) {
+	return TaggedUnparse(d)
 }
 
 // ListArgSemNode is a semantic node that has a list of children, each of which is a content block.
 // It only partially implements the SemNode interface, as it does not implement SyntaxType.
 type ListArgSemNode struct {
-	Contents []*ContentBlockSemNode
+	Contents []*ContentBlockSemNode `obt:"0,content,variadic"`
 }
 
 // ParseArgs implements the SemNode interface.
 func (l *ListArgSemNode) ParseArgs(args []*ContentBlockSemNode) error {
-	l.Contents = args
-	return nil
+	return ParseTaggedArgs(l, args)
 }
 
 // Children implements the SemNode interface.
 func (l *ListArgSemNode) Children() []SemNode {
-	res := make([]SemNode, len(l.Contents))
-	for i, c := range l.Contents {
-		res[i] = c
-	}
-	return res
+	return TaggedChildren(l)
+}
+
+// SetChild implements the MutableSemNode interface.
+func (l *ListArgSemNode) SetChild(i int, n SemNode) error {
+	return TaggedSetChild(l, i, n)
+}
+
+// Unparse implements the UnparsableSemNode interface.
+func (l *ListArgSemNode) Unparse() ([]*ContentBlockSemNode, error) {
+	return TaggedUnparse(l)
 }
 
 // DualArgSemNode is a semantic node that has exactly 2 children, both of which are futher content.
 type DualArgSemNode struct {
-	Arg1 *ContentBlockSemNode
-	Arg2 *ContentBlockSemNode
+	Arg1 *ContentBlockSemNode `obt:"0,content"`
+	Arg2 *ContentBlockSemNode `obt:"1,content"`
 }
 
 // ParseArgs implements the SemNode interface.
 func (d *DualArgSemNode) ParseArgs(args []*ContentBlockSemNode) error {
-	if len(args) != 2 {
-		return fmt.Errorf("dual arg must have exactly 2 children")
-	}
-	d.Arg1 = args[0]
-	d.Arg2 = args[1]
-	return nil
+	return ParseTaggedArgs(d, args)
 }
 
 // Children implements the SemNode interface.
 func (d *DualArgSemNode) Children() []SemNode {
-	return []SemNode{d.Arg1, d.Arg2}
+	return TaggedChildren(d)
+}
+
+// SetChild implements the MutableSemNode interface.
+func (d *DualArgSemNode) SetChild(i int, n SemNode) error {
+	return TaggedSetChild(d, i, n)
+}
+
+// Unparse implements the UnparsableSemNode interface.
+func (d *DualArgSemNode) Unparse() ([]*ContentBlockSemNode, error) {
+	return TaggedUnparse(d)
+}
+
+// CaptionedMediaSemNode is like CaptionedLinkSemNode, but also has an optional third child, which is a string of alternative text.
+// If the alternative text is not given, it is empty.
+// It only partially implements the SemNode interface, as it does not implement SyntaxType.
+type CaptionedMediaSemNode struct {
+	CaptionedLinkSemNode
+	Alt string `obt:"2,text,optional"`
+}
+
+// ParseArgs implements the SemNode interface.
+func (c *CaptionedMediaSemNode) ParseArgs(args []*ContentBlockSemNode) error {
+	return ParseTaggedArgs(c, args)
+}
+
+// Children implements the SemNode interface.
+func (c *CaptionedMediaSemNode) Children() []SemNode {
+	return TaggedChildren(c)
+}
+
+// SetChild implements the MutableSemNode interface.
+func (c *CaptionedMediaSemNode) SetChild(i int, n SemNode) error {
+	return TaggedSetChild(c, i, n)
+}
+
+// Unparse implements the UnparsableSemNode interface.
+func (c *CaptionedMediaSemNode) Unparse() ([]*ContentBlockSemNode, error) {
+	return TaggedUnparse(c)
 }
@@ -0,0 +1,88 @@
+package obtext
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testMedia struct {
+	CaptionedMediaSemNode
+}
+
+func (*testMedia) SyntaxType() string { return "media" }
+
+func parseBasesSem(t *testing.T, src string) (SemNode, error) {
+	t.Helper()
+	return ParseSem(mustParseSyn(t, src), []SemNode{&testMedia{}, &testBold{}})
+}
+
+func mustParseBasesSem(t *testing.T, src string) SemNode {
+	t.Helper()
+	sem, err := parseBasesSem(t, src)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", src, err)
+	}
+	return sem
+}
+
+// assertUnparses checks that n unparses to the source want.
+func assertUnparses(t *testing.T, n SemNode, want string) {
+	t.Helper()
+	syn, err := UnparseSem(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatSynSource(syn); got != want {
+		t.Errorf("unparsed as %q, want %q", got, want)
+	}
+}
+
+func TestCaptionedMediaSemNode(t *testing.T) {
+	m := mustParseBasesSem(t, "@media{A @bold{cat}}{cat.png}{A cat sitting down}").(*testMedia)
+	if textOf(m.CaptionContent) != "A cat" || m.Link != "cat.png" || m.Alt != "A cat sitting down" {
+		t.Errorf("got %+v", m)
+	}
+	if len(m.Children()) != 1 {
+		t.Errorf("got %d children, want only the caption", len(m.Children()))
+	}
+	assertUnparses(t, m, "@media{A @bold{cat}}{cat.png}{A cat sitting down}")
+
+	m = mustParseBasesSem(t, "@media{A cat}{cat.png}").(*testMedia)
+	if m.Alt != "" {
+		t.Errorf("got alt %q when it was not given", m.Alt)
+	}
+	// Missing optional args are not written back
+	assertUnparses(t, m, "@media{A cat}{cat.png}")
+
+	replacement := &ContentBlockSemNode{Elements: []SemNode{&TextSemNode{Text: "x"}}}
+	if err := m.SetChild(0, replacement); err != nil || m.CaptionContent != replacement {
+		t.Errorf("failed to replace the caption: %v", err)
+	}
+}
+
+func TestOptionalBasesErrors(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected string
+		hint     string
+	}{
+		{"@media{a}", "@media must have between 2 and 3 arguments, got 1", "@media needs at least 2 arguments, written as @media{...}{...}"},
+		{"@media{a}{b}{c}{d}", "@media must have between 2 and 3 arguments, got 4", "@media takes at most 3 arguments"},
+		{"@media{a}{b}{@bold{c}}", "@media arg 2 must be text, got object @bold", "arg 2 must not contain objects"},
+	}
+	for _, c := range cases {
+		_, err := parseBasesSem(t, c.src)
+		var semErr *SemError
+		if !errors.As(err, &semErr) {
+			t.Errorf("%s: expected a *SemError, got %v", c.src, err)
+			continue
+		}
+		if semErr.Err.Error() != c.expected {
+			t.Errorf("%s: got %q, want %q", c.src, semErr.Err, c.expected)
+		}
+		if !strings.HasPrefix(semErr.Hint, c.hint) {
+			t.Errorf("%s: got hint %q, want it to start with %q", c.src, semErr.Hint, c.hint)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package obtext
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// argKind is a kind of argument that can be used in an `obt` tag.
+type argKind struct {
+	name string
+	// expected describes what the argument must contain, for use in an ArgTypeError.
+	expected func(f *schemaField) string
+	// accepts returns true if a field of the given type can hold this kind.
+	accepts func(t reflect.Type) bool
+	// cast converts the text of an argument to a value of type t, using the options of the field.
+	// It is nil for content, which is not text.
+	cast func(text string, f *schemaField, t reflect.Type) (reflect.Value, error)
+}
+
+var (
+	contentBlockType = reflect.TypeOf(&ContentBlockSemNode{})
+	urlPtrType       = reflect.TypeOf(&url.URL{})
+	urlType          = reflect.TypeOf(url.URL{})
+	timeType         = reflect.TypeOf(time.Time{})
+)
+
+// fixedExpected returns an expected function that always returns s.
+func fixedExpected(s string) func(*schemaField) string {
+	return func(*schemaField) string { return s }
+}
+
+func isString(t reflect.Type) bool {
+	return t.Kind() == reflect.String
+}
+
+var argKinds = map[string]argKind{
+	"content": {
+		name:     "content",
+		expected: fixedExpected("content"),
+		accepts:  func(t reflect.Type) bool { return t == contentBlockType },
+	},
+	"text": {
+		name:     "text",
+		expected: fixedExpected("text"),
+		accepts:  isString,
+		cast: func(text string, f *schemaField, t reflect.Type) (reflect.Value, error) {
+			return reflect.ValueOf(text).Convert(t), nil
+		},
+	},
+	"int": {
+		name:     "int",
+		expected: fixedExpected("an integer"),
+		accepts: func(t reflect.Type) bool {
+			switch t.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return true
+			}
+			return false
+		},
+		cast: func(text string, f *schemaField, t reflect.Type) (reflect.Value, error) {
+			i, err := strconv.ParseInt(strings.TrimSpace(text), 10, t.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(i).Convert(t), nil
+		},
+	},
+	"float": {
+		name:     "float",
+		expected: fixedExpected("a number"),
+		accepts: func(t reflect.Type) bool {
+			return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+		},
+		cast: func(text string, f *schemaField, t reflect.Type) (reflect.Value, error) {
+			n, err := strconv.ParseFloat(strings.TrimSpace(text), t.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(n).Convert(t), nil
+		},
+	},
+	"bool": {
+		name:     "bool",
+		expected: fixedExpected("true or false"),
+		accepts:  func(t reflect.Type) bool { return t.Kind() == reflect.Bool },
+		cast: func(text string, f *schemaField, t reflect.Type) (reflect.Value, error) {
+			b, err := strconv.ParseBool(strings.TrimSpace(text))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(b).Convert(t), nil
+		},
+	},
+	"url": {
+		name: "url",
+		expected: func(f *schemaField) string {
+			if len(f.schemes) > 0 {
+				return "a URL with scheme " + strings.Join(f.schemes, " or ")
+			}
+			return "a URL"
+		},
+		accepts: func(t reflect.Type) bool {
+			return t == urlPtrType || t == urlType || isString(t)
+		},
+		cast: func(text string, f *schemaField, t reflect.Type) (reflect.Value, error) {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				return reflect.Value{}, errors.New("empty url")
+			}
+			u, err := url.Parse(text)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if len(f.schemes) > 0 && !slices.Contains(f.schemes, strings.ToLower(u.Scheme)) {
+				return reflect.Value{}, fmt.Errorf("scheme '%s' is not allowed", u.Scheme)
+			}
+			switch t {
+			case urlPtrType:
+				return reflect.ValueOf(u), nil
+			case urlType:
+				return reflect.ValueOf(*u), nil
+			}
+			return reflect.ValueOf(text).Convert(t), nil
+		},
+	},
+	"date": {
+		name:     "date",
+		expected: func(f *schemaField) string { return "a date in the form " + f.format },
+		accepts:  func(t reflect.Type) bool { return t == timeType },
+		cast: func(text string, f *schemaField, t reflect.Type) (reflect.Value, error) {
+			d, err := time.Parse(f.format, strings.TrimSpace(text))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(d), nil
+		},
+	},
+	"enum": {
+		name:     "enum",
+		expected: func(f *schemaField) string { return "one of " + strings.Join(f.values, ", ") },
+		accepts:  isString,
+		cast: func(text string, f *schemaField, t reflect.Type) (reflect.Value, error) {
+			text = strings.TrimSpace(text)
+			if !slices.Contains(f.values, text) {
+				return reflect.Value{}, fmt.Errorf("'%s' is not an allowed value", text)
+			}
+			return reflect.ValueOf(text).Convert(t), nil
+		},
+	},
+	"path": {
+		name:     "path",
+		expected: fixedExpected("a file path"),
+		accepts:  isString,
+		cast: func(text string, f *schemaField, t reflect.Type) (reflect.Value, error) {
+			text = strings.TrimSpace(text)
+			if text == "" || strings.ContainsRune(text, 0) {
+				return reflect.Value{}, errors.New("invalid path")
+			}
+			return reflect.ValueOf(path.Clean(text)).Convert(t), nil
+		},
+	},
+}
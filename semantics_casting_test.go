@@ -0,0 +1,164 @@
+package obtext
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testPost struct {
+	Count   int       `obt:"0,int"`
+	Ratio   float64   `obt:"1,float"`
+	Draft   bool      `obt:"2,bool"`
+	Link    *url.URL  `obt:"3,url,schemes=https"`
+	Date    time.Time `obt:"4,date"`
+	Status  string    `obt:"5,enum,values=draft|live"`
+	File    string    `obt:"6,path"`
+	Slug    string    `obt:"7,text,match=^[a-z-]+$"`
+	Authors []string  `obt:"8,text,variadic"`
+}
+
+func (*testPost) SyntaxType() string { return "post" }
+
+func (p *testPost) ParseArgs(args []*ContentBlockSemNode) error {
+	return ParseTaggedArgs(p, args)
+}
+
+func (p *testPost) Children() []SemNode {
+	return TaggedChildren(p)
+}
+
+func (p *testPost) Unparse() ([]*ContentBlockSemNode, error) {
+	return TaggedUnparse(p)
+}
+
+func TestCastKinds(t *testing.T) {
+	p := &testPost{}
+	err := ParseTaggedArgs(p, textArgs("12", " 0.5 ", "true", "https://example.com/a", "2024-02-29", "live", "a/../b//c.go", "my-post", "ann", "bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &testPost{
+		Count:   12,
+		Ratio:   0.5,
+		Draft:   true,
+		Link:    &url.URL{Scheme: "https", Host: "example.com", Path: "/a"},
+		Date:    time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		Status:  "live",
+		File:    "b/c.go",
+		Slug:    "my-post",
+		Authors: []string{"ann", "bob"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+}
+
+func TestCastErrors(t *testing.T) {
+	valid := []string{"12", "0.5", "true", "https://example.com", "2024-02-29", "live", "a.go", "slug"}
+	cases := []struct {
+		arg      int
+		text     string
+		expected string
+	}{
+		{0, "twelve", "an integer"},
+		{1, "half", "a number"},
+		{2, "yes", "true or false"},
+		{3, "http://example.com", "a URL with scheme https"},
+		{4, "29/02/2024", "a date in the form 2006-01-02"},
+		{5, "dead", "one of draft, live"},
+		{7, "Not A Slug", "text matching ^[a-z-]+$"},
+	}
+	for _, c := range cases {
+		texts := append([]string{}, valid...)
+		texts[c.arg] = c.text
+		err := ParseTaggedArgs(&testPost{}, textArgs(texts...))
+		var typeErr *ArgTypeError
+		if !errors.As(err, &typeErr) {
+			t.Errorf("arg %d %q: expected an ArgTypeError, got %v", c.arg, c.text, err)
+			continue
+		}
+		if typeErr.Arg != c.arg || typeErr.Expected != c.expected {
+			t.Errorf("arg %d %q: got %+v, want arg %d expecting %q", c.arg, c.text, typeErr, c.arg, c.expected)
+		}
+	}
+}
+
+func TestCastValueIsStoredOnSemanticArgs(t *testing.T) {
+	var args []*ContentBlockSemNode
+	reg, err := NewRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.RegisterFactory(func() SemNode { return &capturePost{args: &args} }); err != nil {
+		t.Fatal(err)
+	}
+	syn := mustParseSyn(t, "@post{7}{1.5}{false}{https://a.b}{2020-01-02}{draft}{x}{y}")
+	sem, err := reg.Parse(syn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sem.(*capturePost).Count != 7 {
+		t.Errorf("the node was not parsed from the cast values")
+	}
+	if len(args) != 8 || args[0].CastValue != 7 || args[1].CastValue != 1.5 || args[2].CastValue != false || args[5].CastValue != "draft" {
+		t.Errorf("the cast values were not stored on the semantic args: %v", args)
+	}
+	for i, a := range syn.Args {
+		if a.CastValue != args[i].CastValue {
+			t.Errorf("syntax arg %d has cast value %v, want %v", i, a.CastValue, args[i].CastValue)
+		}
+	}
+}
+
+func TestSyntaxTreeCanBeParsedWithDifferentSemantics(t *testing.T) {
+	syn := mustParseSyn(t, "@n{5}")
+	intSem, err := ParseSem(syn, []SemNode{&testIntNode{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	textSem, err := ParseSem(syn, []SemNode{&testTextNode{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intSem.(*testIntNode).N != 5 || textSem.(*testTextNode).S != "5" {
+		t.Errorf("got %+v and %+v", intSem, textSem)
+	}
+	// The syntax tree holds the values from the latest parse
+	if syn.Args[0].CastValue != "5" {
+		t.Errorf("got cast value %v after parsing as text", syn.Args[0].CastValue)
+	}
+}
+
+// capturePost records the args it is parsed from.
+type capturePost struct {
+	testPost
+	args *[]*ContentBlockSemNode
+}
+
+func (p *capturePost) ParseArgs(args []*ContentBlockSemNode) error {
+	*p.args = args
+	return ParseTaggedArgs(p, args)
+}
+
+type testIntNode struct {
+	N int `obt:"0,int"`
+}
+
+func (*testIntNode) SyntaxType() string { return "n" }
+
+func (n *testIntNode) ParseArgs(args []*ContentBlockSemNode) error { return ParseTaggedArgs(n, args) }
+
+func (n *testIntNode) Children() []SemNode { return nil }
+
+type testTextNode struct {
+	S string `obt:"0,text"`
+}
+
+func (*testTextNode) SyntaxType() string { return "n" }
+
+func (n *testTextNode) ParseArgs(args []*ContentBlockSemNode) error { return ParseTaggedArgs(n, args) }
+
+func (n *testTextNode) Children() []SemNode { return nil }
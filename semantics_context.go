@@ -0,0 +1,62 @@
+package obtext
+
+// ContextSemNode is an optional interface for SemNodes that need to know where they are in the document when parsing their args.
+// If a node implements it, ParseSem calls ParseArgsWithContext instead of ParseArgs.
+type ContextSemNode interface {
+	SemNode
+	// ParseArgsWithContext is like ParseArgs, but also receives the context of the object being parsed.
+	ParseArgsWithContext(ctx *ParseContext, args []*ContentBlockSemNode) error
+}
+
+// ParseContext describes where an object is in the document while it is being parsed, and gives access to state shared by the whole parse.
+// Objects are parsed after all of their children, so the parent has not been parsed yet and is only available as a syntax node.
+// This also means that shared state is seen by objects in the order that their closing braces appear in the source.
+type ParseContext struct {
+	// Node is the syntax node of the object being parsed.
+	Node *ObjectSynNode
+	// Parent is the syntax node of the parent object, or nil if the object is the root.
+	Parent *ObjectSynNode
+	// Path is the path of objects from the root to the object being parsed (inclusive).
+	Path ObjectPath
+	// Metadata is the document metadata given with WithMetadata, which may be nil.
+	Metadata map[string]any
+	store    map[any]any
+}
+
+// Pos returns the position of the object being parsed.
+func (c *ParseContext) Pos() Position {
+	return c.Node.Pos
+}
+
+// Depth returns the number of objects that the object being parsed is inside of, so the root has a depth of 0.
+func (c *ParseContext) Depth() int {
+	return len(c.Path) - 1
+}
+
+// Arg returns the index of the argument of the parent that the object is in, or -1 if it is the root.
+func (c *ParseContext) Arg() int {
+	return c.Path[len(c.Path)-1].Arg
+}
+
+// Index returns the index of the object among its siblings in the argument of the parent, or -1 if it is the root.
+func (c *ParseContext) Index() int {
+	return c.Path[len(c.Path)-1].Index
+}
+
+// Value returns the value stored for the key in this parse, or nil if there is none.
+func (c *ParseContext) Value(key any) any {
+	return c.store[key]
+}
+
+// SetValue stores a value for the key, which can be read by any object parsed after this one in the same parse.
+// As with context.Context, keys should be of an unexported type to avoid collisions between packages.
+func (c *ParseContext) SetValue(key, value any) {
+	c.store[key] = value
+}
+
+// WithMetadata gives ParseSem document metadata, such as a title or author, that ContextSemNodes can read from their ParseContext.
+func WithMetadata(metadata map[string]any) SemOption {
+	return func(p *semParser) {
+		p.metadata = metadata
+	}
+}
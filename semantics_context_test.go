@@ -0,0 +1,93 @@
+package obtext
+
+import (
+	"reflect"
+	"testing"
+)
+
+// figureCountKey is the key that testNumbered uses to count figures in the ParseContext.
+type figureCountKey struct{}
+
+// testNumbered is numbered in the order it is parsed, and records its context.
+type testNumbered struct {
+	SingleArgSemNode
+	Number   int
+	Depth    int
+	Arg      int
+	Index    int
+	Line     int
+	Parent   string
+	Author   any
+	PathText string
+}
+
+func (*testNumbered) SyntaxType() string { return "num" }
+
+func (n *testNumbered) ParseArgsWithContext(ctx *ParseContext, args []*ContentBlockSemNode) error {
+	count, _ := ctx.Value(figureCountKey{}).(int)
+	count++
+	ctx.SetValue(figureCountKey{}, count)
+	n.Number = count
+	n.Depth = ctx.Depth()
+	n.Arg = ctx.Arg()
+	n.Index = ctx.Index()
+	n.Line = ctx.Pos().Line
+	if ctx.Parent != nil {
+		n.Parent = ctx.Parent.Type
+	}
+	n.Author = ctx.Metadata["author"]
+	n.PathText = ctx.Path.String()
+	return n.ParseArgs(args)
+}
+
+func TestParseContext(t *testing.T) {
+	src := "@doc{\n@num{a}\n@para{x @num{b} y}\n@num{c @num{d}}\n}"
+	semantics := append(testSemantics(), &testNumbered{})
+	sem, err := ParseSem(mustParseSyn(t, src), semantics, WithMetadata(map[string]any{"author": "ann"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []testNumbered
+	Walk(sem, func(n, _ SemNode) bool {
+		if num, ok := n.(*testNumbered); ok {
+			c := *num
+			c.SingleArgSemNode = SingleArgSemNode{}
+			got = append(got, c)
+		}
+		return true
+	})
+	// Objects are parsed after their children, so @d is numbered before @c
+	expected := []testNumbered{
+		{Number: 1, Depth: 1, Arg: 0, Index: 0, Line: 2, Parent: "doc", Author: "ann", PathText: "doc > num[0]"},
+		{Number: 2, Depth: 2, Arg: 0, Index: 1, Line: 3, Parent: "para", Author: "ann", PathText: "doc > para[1] > num[1]"},
+		{Number: 4, Depth: 1, Arg: 0, Index: 2, Line: 4, Parent: "doc", Author: "ann", PathText: "doc > num[2]"},
+		{Number: 3, Depth: 2, Arg: 0, Index: 1, Line: 4, Parent: "num", Author: "ann", PathText: "doc > num[2] > num[1]"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %+v\nwant %+v", got, expected)
+	}
+}
+
+func TestParseContextOfRoot(t *testing.T) {
+	sem, err := ParseSem(mustParseSyn(t, "@num{a}"), []SemNode{&testNumbered{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := sem.(*testNumbered)
+	if n.Depth != 0 || n.Parent != "" || n.Author != nil || n.Number != 1 {
+		t.Errorf("got %+v", n)
+	}
+}
+
+func TestParseContextStateIsPerParse(t *testing.T) {
+	semantics := []SemNode{&testNumbered{}}
+	for i := 0; i < 2; i++ {
+		sem, err := ParseSem(mustParseSyn(t, "@num{a}"), semantics)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := sem.(*testNumbered).Number; n != 1 {
+			t.Errorf("parse %d: got number %d, want 1", i, n)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package obtext
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// DescribedSemNode is an optional interface for SemNodes that describe themselves, which is used by Registry.Schema to generate documentation.
+type DescribedSemNode interface {
+	SemNode
+	// Describe returns documentation for the node.
+	Describe() NodeDoc
+}
+
+// NodeDoc is the documentation that a DescribedSemNode gives about itself.
+type NodeDoc struct {
+	// Summary is a short description of what the node is for.
+	Summary string
+	// Args describes each arg of the node. If empty, the args are found from the `obt` tags of the node, if it has any.
+	// If only some fields of an arg are set, the rest are also filled in from the tags.
+	Args []ArgSchema
+	// Examples are snippets of obtext that use the node.
+	Examples []string
+}
+
+// ArgSchema describes a single arg of an object.
+type ArgSchema struct {
+	// Name is a short name for the arg, such as 'caption'.
+	Name string `json:"name"`
+	// Kind is what the arg must contain, which is one of the kinds that can be used in `obt` tags, such as 'content' or 'int'.
+	Kind string `json:"kind"`
+	// Description is a longer description of the arg.
+	Description string `json:"description,omitempty"`
+	// Optional is true if the arg can be left out.
+	Optional bool `json:"optional,omitempty"`
+	// Variadic is true if the arg can be repeated any number of times, including none.
+	Variadic bool `json:"variadic,omitempty"`
+	// Default is the value used for an optional arg that is left out.
+	Default string `json:"default,omitempty"`
+	// Allow is the list of categories and syntax types that may be used in the arg, from the content model of the object.
+	Allow []string `json:"allow,omitempty"`
+}
+
+// ObjectSchema is a machine readable description of an object in a Registry.
+type ObjectSchema struct {
+	// Name is the name that the object is registered under.
+	Name string `json:"name"`
+	// Aliases are other names for the object.
+	Aliases []string `json:"aliases,omitempty"`
+	// Summary is a short description of the object.
+	Summary string `json:"summary,omitempty"`
+	// Args describes each arg of the object, which may be empty if the object does not describe itself and has no `obt` tags.
+	Args []ArgSchema `json:"args"`
+	// MinArgs is the minimum number of args, or -1 if it is not known.
+	MinArgs int `json:"minArgs"`
+	// MaxArgs is the maximum number of args, or -1 if there is no maximum or it is not known.
+	MaxArgs int `json:"maxArgs"`
+	// Examples are snippets of obtext that use the object.
+	Examples []string `json:"examples,omitempty"`
+	// Categories are the content categories of the object, from its content model.
+	Categories []string `json:"categories,omitempty"`
+	// Parents are the only objects that this object may be directly inside of, from its content model.
+	Parents []string `json:"parents,omitempty"`
+}
+
+// Usage returns the object written with the name of each arg, such as '@img{caption}{url}[{alt}]'.
+// Optional args are in square brackets, and variadic args are followed by '...'.
+func (s ObjectSchema) Usage() string {
+	out := "@" + s.Name
+	for _, a := range s.Args {
+		switch {
+		case a.Variadic:
+			out += fmt.Sprintf("{%s}...", a.Name)
+		case a.Optional:
+			out += fmt.Sprintf("[{%s}]", a.Name)
+		default:
+			out += fmt.Sprintf("{%s}", a.Name)
+		}
+	}
+	return out
+}
+
+// Schema describes every object in the registry, sorted by name, which can be used to generate documentation or be encoded as JSON for tools.
+// Aliases are listed on the object that they refer to, rather than as separate objects.
+func (r *Registry) Schema() []ObjectSchema {
+	aliases := make(map[string][]string)
+	for alias, target := range r.aliases {
+		aliases[target] = append(aliases[target], alias)
+	}
+	res := make([]ObjectSchema, 0, len(r.nodes))
+	for name, e := range r.nodes {
+		s := schemaOf(e.proto)
+		s.Name = name
+		s.Aliases = aliases[name]
+		sort.Strings(s.Aliases)
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// schemaOf builds the schema of a node from its documentation, tags and content model.
+func schemaOf(n SemNode) ObjectSchema {
+	s := ObjectSchema{Args: tagArgSchemas(n), MinArgs: -1, MaxArgs: -1}
+	g, isGeneric := n.(genericSemNode)
+	if isGeneric {
+		s.Args = genericArgSchemas(g)
+	}
+	if d, ok := n.(DescribedSemNode); ok {
+		doc := d.Describe()
+		s.Summary = doc.Summary
+		s.Examples = doc.Examples
+		if len(s.Args) == 0 {
+			s.Args = doc.Args
+		} else {
+			for i := range doc.Args {
+				if i < len(s.Args) {
+					s.Args[i] = mergeArgSchema(doc.Args[i], s.Args[i])
+				}
+			}
+		}
+	}
+	if isGeneric {
+		// An ArgType may consume any number of args, so the count comes from their arity rather than their schemas
+		s.MinArgs, s.MaxArgs = genericArity(g.slots())
+	} else if len(s.Args) > 0 || isTagged(n) {
+		s.MinArgs, s.MaxArgs = 0, len(s.Args)
+		for _, a := range s.Args {
+			if a.Variadic {
+				s.MaxArgs = -1
+			} else if !a.Optional {
+				s.MinArgs++
+			}
+		}
+	}
+	if m := contentModelOf(n); m != nil {
+		s.Categories = m.Categories
+		s.Parents = m.Parents
+		for i := range s.Args {
+			if rule := m.forArg(i); rule != nil {
+				s.Args[i].Allow = rule.Allow
+			}
+		}
+	}
+	return s
+}
+
+// mergeArgSchema fills in any empty fields of a with those from b.
+func mergeArgSchema(a, b ArgSchema) ArgSchema {
+	if a.Name == "" {
+		a.Name = b.Name
+	}
+	if a.Kind == "" {
+		a.Kind = b.Kind
+	}
+	if a.Description == "" {
+		a.Description = b.Description
+	}
+	if a.Default == "" {
+		a.Default = b.Default
+	}
+	a.Optional = a.Optional || b.Optional
+	a.Variadic = a.Variadic || b.Variadic
+	return a
+}
+
+// isTagged returns true if the node has `obt` tags.
+func isTagged(n SemNode) bool {
+	_, ok := taggedSchemaFor(reflect.TypeOf(n))
+	return ok
+}
+
+// tagArgSchemas returns the schema of each arg from the `obt` tags of the node, or nil if it has none.
+func tagArgSchemas(n SemNode) []ArgSchema {
+	s, ok := taggedSchemaFor(reflect.TypeOf(n))
+	if !ok {
+		return nil
+	}
+	res := make([]ArgSchema, len(s.fields))
+	for i, f := range s.fields {
+		res[i] = ArgSchema{
+			Name:     f.name,
+			Kind:     f.kind.name,
+			Optional: !f.required && !f.variadic,
+			Variadic: f.variadic,
+		}
+		if f.kind.cast != nil {
+			res[i].Description = "must be " + f.expected()
+		}
+		if res[i].Optional {
+			if def := f.def(); !def.IsZero() {
+				if arg, err := unparseField(&f, def); err == nil {
+					res[i].Default, _ = textArg([]*ContentBlockSemNode{arg}, 0)
+				}
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,125 @@
+package obtext
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// testDescribed describes itself, filling in only some fields of its args so the rest come from its tags.
+type testDescribed struct {
+	Caption *ContentBlockSemNode   `obt:"0,content"`
+	Width   int                    `obt:"1,int,optional,default=50"`
+	Extras  []*ContentBlockSemNode `obt:"2,content,variadic"`
+}
+
+func (*testDescribed) SyntaxType() string { return "described" }
+
+func (d *testDescribed) ParseArgs(args []*ContentBlockSemNode) error {
+	return ParseTaggedArgs(d, args)
+}
+
+func (d *testDescribed) Children() []SemNode {
+	return TaggedChildren(d)
+}
+
+func (*testDescribed) Describe() NodeDoc {
+	return NodeDoc{
+		Summary: "A described node.",
+		Args: []ArgSchema{
+			{Name: "caption", Description: "The caption."},
+			{Name: "width"},
+		},
+		Examples: []string{"@described{A}{20}"},
+	}
+}
+
+func (*testDescribed) ContentModel() ContentModel {
+	return ContentModel{
+		Categories: []string{"block"},
+		Parents:    []string{"article"},
+		Args:       []ArgContentModel{{Allow: []string{TextContent, "inline"}}},
+	}
+}
+
+// schemaByName returns the schema of each object in the registry by its name.
+func schemaByName(t *testing.T, reg *Registry) map[string]ObjectSchema {
+	t.Helper()
+	res := make(map[string]ObjectSchema)
+	for _, s := range reg.Schema() {
+		res[s.Name] = s
+	}
+	return res
+}
+
+func TestSchemaFromDescription(t *testing.T) {
+	reg := mustNewRegistry(t, &testDescribed{})
+	if err := reg.Alias("desc", "described"); err != nil {
+		t.Fatal(err)
+	}
+	s := schemaByName(t, reg)["described"]
+	expected := ObjectSchema{
+		Name:    "described",
+		Aliases: []string{"desc"},
+		Summary: "A described node.",
+		Args: []ArgSchema{
+			{Name: "caption", Kind: "content", Description: "The caption.", Allow: []string{TextContent, "inline"}},
+			{Name: "width", Kind: "int", Description: "must be an integer", Optional: true, Default: "50", Allow: []string{TextContent, "inline"}},
+			// Args without a name in the description are named after their field, and the last content model rule applies to any later args
+			{Name: "Extras", Kind: "content", Variadic: true, Allow: []string{TextContent, "inline"}},
+		},
+		MinArgs:    1,
+		MaxArgs:    -1,
+		Examples:   []string{"@described{A}{20}"},
+		Categories: []string{"block"},
+		Parents:    []string{"article"},
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("got\n%+v\nwant\n%+v", s, expected)
+	}
+	if got := s.Usage(); got != "@described{caption}[{width}]{Extras}..." {
+		t.Errorf("got usage %q", got)
+	}
+}
+
+func TestSchemaFromTags(t *testing.T) {
+	s := schemaByName(t, mustNewRegistry(t, &testHeading{}))["heading"]
+	if s.Summary != "" || s.MinArgs != 1 || s.MaxArgs != 3 || len(s.Args) != 3 {
+		t.Fatalf("got %+v", s)
+	}
+	if s.Args[1].Default != "2" || !s.Args[1].Optional || s.Args[2].Default != "" {
+		t.Errorf("got args %+v", s.Args)
+	}
+	if got := s.Usage(); got != "@heading{Title}[{Level}][{Label}]" {
+		t.Errorf("got usage %q", got)
+	}
+}
+
+func TestSchemaUndescribed(t *testing.T) {
+	// Nodes without tags or a description have unknown args
+	reg := mustNewRegistry(t, &testFrozen{}, &testTitle{})
+	schemas := reg.Schema()
+	if len(schemas) != 2 || schemas[0].Name != "frozen" || schemas[1].Name != "title" {
+		t.Fatalf("schemas are not sorted by name: %+v", schemas)
+	}
+	if s := schemas[0]; len(s.Args) != 0 || s.MinArgs != -1 || s.MaxArgs != -1 || s.Usage() != "@frozen" {
+		t.Errorf("got %+v", s)
+	}
+	if s := schemas[1]; s.MinArgs != 1 || s.MaxArgs != 1 || !reflect.DeepEqual(s.Args[0].Allow, []string{TextContent}) {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestSchemaJSON(t *testing.T) {
+	data, err := json.Marshal(mustNewRegistry(t, &testDescribed{}).Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []ObjectSchema
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[0].Args[1].Default != "50" || decoded[0].MaxArgs != -1 {
+		t.Errorf("got %s", data)
+	}
+}
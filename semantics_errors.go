@@ -0,0 +1,207 @@
+package obtext
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PathElement is a single step in an ObjectPath.
+type PathElement struct {
+	// Type is the syntax type of the object, i.e. '@<type>{...}'.
+	Type string
+	// Arg is the index of the argument of the parent object that this object is in, or -1 for the root object.
+	Arg int
+	// Index is the index of this object within that argument, or -1 for the root object.
+	Index int
+}
+
+// ObjectPath is the list of objects enclosing a node, starting from the root object.
+type ObjectPath []PathElement
+
+// String returns the path in the form 'doc > section[2] > para[4] > link'.
+func (p ObjectPath) String() string {
+	parts := make([]string, len(p))
+	for i, e := range p {
+		if e.Index < 0 {
+			parts[i] = e.Type
+		} else {
+			parts[i] = fmt.Sprintf("%s[%d]", e.Type, e.Index)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// with returns a copy of the path with the given element appended.
+func (p ObjectPath) with(e PathElement) ObjectPath {
+	np := make(ObjectPath, len(p), len(p)+1)
+	copy(np, p)
+	return append(np, e)
+}
+
+// SemError is an error that occurred while parsing the semantics of a single object.
+// All errors returned by ParseSem are either a SemError or, when collecting all errors, a join of SemErrors.
+type SemError struct {
+	// Pos is the position of the object that caused the error.
+	Pos Position
+	// Path is the path of objects from the root to the object that caused the error (inclusive).
+	Path ObjectPath
+	// Err is the underlying error.
+	Err error
+	// Hint is a suggestion about a common mistake that may have caused the error, or empty if there is none.
+	Hint string
+}
+
+// Error implements the error interface.
+func (e *SemError) Error() string {
+	msg := fmt.Sprintf("%s: %s: %s", e.Pos, e.Path, e.Err)
+	if e.Hint != "" {
+		msg += " (hint: " + e.Hint + ")"
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *SemError) Unwrap() error {
+	return e.Err
+}
+
+// SemErrors returns all of the SemErrors contained in an error returned by ParseSem.
+// This is useful when ParseSem was called with WithAllErrors, to get a list of diagnostics.
+func SemErrors(err error) []*SemError {
+	if err == nil {
+		return nil
+	}
+	if se, ok := err.(*SemError); ok {
+		return []*SemError{se}
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		res := make([]*SemError, 0)
+		for _, e := range joined.Unwrap() {
+			res = append(res, SemErrors(e)...)
+		}
+		return res
+	}
+	var se *SemError
+	if errors.As(err, &se) {
+		return []*SemError{se}
+	}
+	return nil
+}
+
+// UnknownObjectError is used when an object in the syntax tree has a type that is not in the semantics.
+type UnknownObjectError struct {
+	// Type is the syntax type of the unknown object.
+	Type string
+	// Suggestions are the closest defined syntax types, closest first.
+	// It may be empty if no defined type is close enough.
+	Suggestions []string
+}
+
+// Error implements the error interface.
+func (e *UnknownObjectError) Error() string {
+	msg := fmt.Sprintf("object '%s' was not defined", e.Type)
+	if len(e.Suggestions) > 0 {
+		msg += ", did you mean @" + strings.Join(e.Suggestions, ", @") + "?"
+	}
+	return msg
+}
+
+// ArgCountError is used when an object is given the wrong number of arguments.
+type ArgCountError struct {
+	// Type is the syntax type of the object.
+	// If this is left empty by ParseArgs, ParseSem will fill it in.
+	Type string
+	// Min is the minimum number of arguments accepted.
+	Min int
+	// Max is the maximum number of arguments accepted, or -1 if there is no maximum.
+	Max int
+	// Got is the number of arguments that were given.
+	Got int
+}
+
+// Error implements the error interface.
+func (e *ArgCountError) Error() string {
+	var expected string
+	switch {
+	case e.Min == e.Max:
+		expected = fmt.Sprintf("exactly %d", e.Min)
+	case e.Max < 0:
+		expected = fmt.Sprintf("at least %d", e.Min)
+	default:
+		expected = fmt.Sprintf("between %d and %d", e.Min, e.Max)
+	}
+	noun := "arguments"
+	if e.Min == 1 && (e.Max == 1 || e.Max < 0) {
+		noun = "argument"
+	}
+	return fmt.Sprintf("%s must have %s %s, got %d", objectName(e.Type), expected, noun, e.Got)
+}
+
+// ArgTypeError is used when an argument of an object has the wrong content, for example an object where text was expected.
+type ArgTypeError struct {
+	// Type is the syntax type of the object.
+	// If this is left empty by ParseArgs, ParseSem will fill it in.
+	Type string
+	// Arg is the index of the offending argument, starting at 0.
+	Arg int
+	// Expected describes what the argument should contain, for example 'text'.
+	Expected string
+	// Got describes what the argument actually contained.
+	Got string
+}
+
+// Error implements the error interface.
+func (e *ArgTypeError) Error() string {
+	return fmt.Sprintf("%s arg %d must be %s, got %s", objectName(e.Type), e.Arg, e.Expected, e.Got)
+}
+
+// objectName formats an object type for use in an error message.
+func objectName(t string) string {
+	if t == "" {
+		return "object"
+	}
+	return "@" + t
+}
+
+// typedSemError is implemented by errors that contain the type of the failing object.
+type typedSemError interface {
+	error
+	setType(t string)
+}
+
+func (e *ArgCountError) setType(t string) {
+	if e.Type == "" {
+		e.Type = t
+	}
+}
+
+func (e *ArgTypeError) setType(t string) {
+	if e.Type == "" {
+		e.Type = t
+	}
+}
+
+// describeContent returns a short description of the content of an argument, for use in an ArgTypeError.
+func describeContent(c *ContentBlockSemNode) string {
+	switch len(c.Elements) {
+	case 0:
+		return "nothing"
+	case 1:
+		return describeNode(c.Elements[0])
+	default:
+		return fmt.Sprintf("%d elements", len(c.Elements))
+	}
+}
+
+// describeNode returns a short description of a single node, for use in an ArgTypeError.
+func describeNode(n SemNode) string {
+	switch n := n.(type) {
+	case *TextSemNode:
+		return "text"
+	case *ContentBlockSemNode:
+		return describeContent(n)
+	default:
+		return "object @" + n.SyntaxType()
+	}
+}
@@ -0,0 +1,130 @@
+package obtext
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSemErrorPositionAndPath(t *testing.T) {
+	_, err := parseTestSem(t, "@doc{\n@para{a}\n@para{b @foo{c}}\n}")
+	var semErr *SemError
+	if !errors.As(err, &semErr) {
+		t.Fatalf("expected a *SemError, got %T: %v", err, err)
+	}
+	if want := (Position{Offset: 23, Line: 3, Column: 9}); semErr.Pos != want {
+		t.Errorf("got position %+v, want %+v", semErr.Pos, want)
+	}
+	if got, want := semErr.Path.String(), "doc > para[1] > foo[1]"; got != want {
+		t.Errorf("got path %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(err.Error(), "3:9: doc > para[1] > foo[1]: ") {
+		t.Errorf("error message does not start with the position and path: %q", err)
+	}
+}
+
+func TestObjectPathString(t *testing.T) {
+	p := ObjectPath{{Type: "doc", Arg: -1, Index: -1}, {Type: "section", Arg: 0, Index: 2}, {Type: "link", Arg: 1, Index: 0}}
+	if got, want := p.String(), "doc > section[2] > link[0]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseSemStopsAtFirstError(t *testing.T) {
+	_, err := parseTestSem(t, "@doc{@foo{} @bar{}}")
+	if errs := SemErrors(err); len(errs) != 1 {
+		t.Fatalf("expected exactly one error, got %d: %v", len(errs), err)
+	}
+}
+
+func TestWithAllErrors(t *testing.T) {
+	_, err := parseTestSem(t, "@doc{\n@foo{}\n@para{@bar{}}\n@bold{a}{b}\n}", WithAllErrors())
+	errs := SemErrors(err)
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %d: %v", len(errs), err)
+	}
+	wantLines := []int{2, 3, 4}
+	for i, e := range errs {
+		if e.Pos.Line != wantLines[i] {
+			t.Errorf("error %d: got line %d, want %d", i, e.Pos.Line, wantLines[i])
+		}
+	}
+	for _, e := range errs {
+		if !strings.Contains(err.Error(), e.Error()) {
+			t.Errorf("joined error %q does not contain %q", err, e)
+		}
+	}
+}
+
+func TestSemErrorsOfNil(t *testing.T) {
+	if errs := SemErrors(nil); errs != nil {
+		t.Errorf("expected nil, got %v", errs)
+	}
+}
+
+func TestUnknownObjectError(t *testing.T) {
+	_, err := parseTestSem(t, "@doc{@foo{}}")
+	var unknown *UnknownObjectError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected an *UnknownObjectError, got %T: %v", err, err)
+	}
+	if unknown.Type != "foo" {
+		t.Errorf("got type %q, want foo", unknown.Type)
+	}
+}
+
+func TestArgCountError(t *testing.T) {
+	cases := []struct {
+		src  string
+		want ArgCountError
+		msg  string
+	}{
+		{"@doc{@para{a}{b}}", ArgCountError{Type: "para", Min: 1, Max: 1, Got: 2}, "@para must have exactly 1 argument, got 2"},
+		{"@doc{@link{a}}", ArgCountError{Type: "link", Min: 2, Max: 2, Got: 1}, "@link must have exactly 2 arguments, got 1"},
+	}
+	for _, c := range cases {
+		_, err := parseTestSem(t, c.src)
+		var countErr *ArgCountError
+		if !errors.As(err, &countErr) {
+			t.Errorf("%s: expected an *ArgCountError, got %T: %v", c.src, err, err)
+			continue
+		}
+		if *countErr != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.src, *countErr, c.want)
+		}
+		if countErr.Error() != c.msg {
+			t.Errorf("%s: got message %q, want %q", c.src, countErr.Error(), c.msg)
+		}
+	}
+}
+
+func TestArgCountErrorMessages(t *testing.T) {
+	cases := []struct {
+		err  ArgCountError
+		want string
+	}{
+		{ArgCountError{Type: "img", Min: 2, Max: 3, Got: 1}, "@img must have between 2 and 3 arguments, got 1"},
+		{ArgCountError{Type: "list", Min: 1, Max: -1, Got: 0}, "@list must have at least 1 argument, got 0"},
+		{ArgCountError{Min: 0, Max: 0, Got: 1}, "object must have exactly 0 arguments, got 1"},
+	}
+	for _, c := range cases {
+		if got := c.err.Error(); got != c.want {
+			t.Errorf("got %q, want %q", got, c.want)
+		}
+	}
+}
+
+func TestArgTypeError(t *testing.T) {
+	_, err := parseTestSem(t, "@doc{@link{a}{@bold{b}}}")
+	var typeErr *ArgTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected an *ArgTypeError, got %T: %v", err, err)
+	}
+	want := ArgTypeError{Type: "link", Arg: 1, Expected: "text", Got: "object @bold"}
+	if *typeErr != want {
+		t.Errorf("got %+v, want %+v", *typeErr, want)
+	}
+	if got, want := typeErr.Error(), "@link arg 1 must be text, got object @bold"; got != want {
+		t.Errorf("got message %q, want %q", got, want)
+	}
+}
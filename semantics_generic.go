@@ -0,0 +1,569 @@
+package obtext
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// ArgType is implemented by the types that can be used as the type parameters of Args1, Args2, Args3 and Args4:
+// Content, Text, Int, URL, Optional and Variadic.
+// Other kinds of arg can be added by implementing ArgType on a struct, and ArgParser on a pointer to it.
+type ArgType interface {
+	// Arity returns the minimum and maximum number of args consumed, where a maximum of -1 means any number.
+	Arity() (min, max int)
+	// Schema describes the arg, for use in Registry.Schema. Its Name is filled in by the node.
+	Schema() ArgSchema
+	// ContentChildren returns the content blocks held by the arg.
+	ContentChildren() []*ContentBlockSemNode
+	// UnparseArgs returns the args that would parse into this value.
+	UnparseArgs() ([]*ContentBlockSemNode, error)
+}
+
+// ArgParser is implemented by pointers to ArgTypes.
+type ArgParser interface {
+	// ParseArg parses args starting at index start, returning the number of args consumed.
+	// The number of args has already been checked against the arity of every ArgType of the node.
+	ParseArg(args []*ContentBlockSemNode, start int) (int, error)
+	// SetContentChild replaces the i-th content block returned by ContentChildren, returning false if there is none.
+	SetContentChild(i int, c *ContentBlockSemNode) bool
+}
+
+// kindSchema returns the schema of an arg of one of the kinds that can be used in `obt` tags.
+func kindSchema(kind string) ArgSchema {
+	k := argKinds[kind]
+	s := ArgSchema{Kind: k.name}
+	if k.cast != nil {
+		s.Description = "must be " + k.expected(&schemaField{})
+	}
+	return s
+}
+
+// castKindArg casts args[i] to a T according to the kind, which is one of the kinds that can be used in `obt` tags.
+// Like ParseTaggedArgs, it uses the CastValue of the arg if it already has one, and otherwise stores the result there.
+func castKindArg[T any](kind string, args []*ContentBlockSemNode, i int) (T, error) {
+	var v T
+	f := &schemaField{kind: argKinds[kind], elemType: reflect.TypeOf(v)}
+	if err := f.set(reflect.ValueOf(&v).Elem(), args, i); err != nil {
+		return v, err
+	}
+	args[i].CastValue = v
+	return v, nil
+}
+
+// Content is an ArgType for an arg that can contain anything.
+type Content struct {
+	Value *ContentBlockSemNode
+}
+
+// Arity implements the ArgType interface.
+func (Content) Arity() (int, int) { return 1, 1 }
+
+// Schema implements the ArgType interface.
+func (Content) Schema() ArgSchema { return kindSchema("content") }
+
+// ContentChildren implements the ArgType interface.
+func (c Content) ContentChildren() []*ContentBlockSemNode { return []*ContentBlockSemNode{c.Value} }
+
+// UnparseArgs implements the ArgType interface.
+func (c Content) UnparseArgs() ([]*ContentBlockSemNode, error) {
+	return []*ContentBlockSemNode{c.Value}, nil
+}
+
+// ParseArg implements the ArgParser interface.
+func (c *Content) ParseArg(args []*ContentBlockSemNode, start int) (int, error) {
+	c.Value = args[start]
+	return 1, nil
+}
+
+// SetContentChild implements the ArgParser interface.
+func (c *Content) SetContentChild(i int, b *ContentBlockSemNode) bool {
+	if i != 0 {
+		return false
+	}
+	c.Value = b
+	return true
+}
+
+// Text is an ArgType for an arg that must be a single piece of text.
+type Text struct {
+	Value string
+}
+
+// Arity implements the ArgType interface.
+func (Text) Arity() (int, int) { return 1, 1 }
+
+// Schema implements the ArgType interface.
+func (Text) Schema() ArgSchema { return kindSchema("text") }
+
+// ContentChildren implements the ArgType interface.
+func (Text) ContentChildren() []*ContentBlockSemNode { return nil }
+
+// UnparseArgs implements the ArgType interface.
+func (t Text) UnparseArgs() ([]*ContentBlockSemNode, error) {
+	return []*ContentBlockSemNode{textBlock(t.Value)}, nil
+}
+
+// ParseArg implements the ArgParser interface.
+func (t *Text) ParseArg(args []*ContentBlockSemNode, start int) (int, error) {
+	var err error
+	t.Value, err = castKindArg[string]("text", args, start)
+	return 1, err
+}
+
+// SetContentChild implements the ArgParser interface.
+func (t *Text) SetContentChild(int, *ContentBlockSemNode) bool { return false }
+
+// Int is an ArgType for an arg that must be text containing an integer.
+type Int struct {
+	Value int
+}
+
+// Arity implements the ArgType interface.
+func (Int) Arity() (int, int) { return 1, 1 }
+
+// Schema implements the ArgType interface.
+func (Int) Schema() ArgSchema { return kindSchema("int") }
+
+// ContentChildren implements the ArgType interface.
+func (Int) ContentChildren() []*ContentBlockSemNode { return nil }
+
+// UnparseArgs implements the ArgType interface.
+func (n Int) UnparseArgs() ([]*ContentBlockSemNode, error) {
+	return []*ContentBlockSemNode{textBlock(fmt.Sprint(n.Value))}, nil
+}
+
+// ParseArg implements the ArgParser interface.
+func (n *Int) ParseArg(args []*ContentBlockSemNode, start int) (int, error) {
+	var err error
+	n.Value, err = castKindArg[int]("int", args, start)
+	return 1, err
+}
+
+// SetContentChild implements the ArgParser interface.
+func (n *Int) SetContentChild(int, *ContentBlockSemNode) bool { return false }
+
+// URL is an ArgType for an arg that must be text containing a URL.
+type URL struct {
+	Value *url.URL
+}
+
+// Arity implements the ArgType interface.
+func (URL) Arity() (int, int) { return 1, 1 }
+
+// Schema implements the ArgType interface.
+func (URL) Schema() ArgSchema { return kindSchema("url") }
+
+// ContentChildren implements the ArgType interface.
+func (URL) ContentChildren() []*ContentBlockSemNode { return nil }
+
+// UnparseArgs implements the ArgType interface.
+func (u URL) UnparseArgs() ([]*ContentBlockSemNode, error) {
+	if u.Value == nil {
+		return nil, errors.New("the URL has no value")
+	}
+	return []*ContentBlockSemNode{textBlock(u.Value.String())}, nil
+}
+
+// ParseArg implements the ArgParser interface.
+func (u *URL) ParseArg(args []*ContentBlockSemNode, start int) (int, error) {
+	var err error
+	u.Value, err = castKindArg[*url.URL]("url", args, start)
+	return 1, err
+}
+
+// SetContentChild implements the ArgParser interface.
+func (u *URL) SetContentChild(int, *ContentBlockSemNode) bool { return false }
+
+// Optional is an ArgType for an arg of type T that may be left out. It must only be followed by other optional args, or a variadic arg.
+type Optional[T ArgType] struct {
+	Value T
+	// Set is true if the arg was given.
+	Set bool
+}
+
+// Get returns the value and whether it was given.
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Set
+}
+
+// Arity implements the ArgType interface.
+func (o Optional[T]) Arity() (int, int) {
+	_, max := o.Value.Arity()
+	return 0, max
+}
+
+// Schema implements the ArgType interface.
+func (o Optional[T]) Schema() ArgSchema {
+	s := o.Value.Schema()
+	s.Optional = !s.Variadic
+	return s
+}
+
+// ContentChildren implements the ArgType interface.
+func (o Optional[T]) ContentChildren() []*ContentBlockSemNode {
+	if !o.Set {
+		return nil
+	}
+	return o.Value.ContentChildren()
+}
+
+// UnparseArgs implements the ArgType interface.
+func (o Optional[T]) UnparseArgs() ([]*ContentBlockSemNode, error) {
+	if !o.Set {
+		return nil, nil
+	}
+	return o.Value.UnparseArgs()
+}
+
+// ParseArg implements the ArgParser interface.
+func (o *Optional[T]) ParseArg(args []*ContentBlockSemNode, start int) (int, error) {
+	if start >= len(args) {
+		return 0, nil
+	}
+	p, err := argParserOf(&o.Value)
+	if err != nil {
+		return 0, err
+	}
+	n, err := p.ParseArg(args, start)
+	if err != nil {
+		return 0, err
+	}
+	o.Set = true
+	return n, nil
+}
+
+// SetContentChild implements the ArgParser interface.
+func (o *Optional[T]) SetContentChild(i int, c *ContentBlockSemNode) bool {
+	p, err := argParserOf(&o.Value)
+	return err == nil && o.Set && p.SetContentChild(i, c)
+}
+
+// Variadic is an ArgType for any number of args of type T, including none. It must be the last arg.
+type Variadic[T ArgType] struct {
+	Values []T
+}
+
+// Arity implements the ArgType interface.
+func (Variadic[T]) Arity() (int, int) { return 0, -1 }
+
+// Schema implements the ArgType interface.
+func (Variadic[T]) Schema() ArgSchema {
+	var v T
+	s := v.Schema()
+	s.Optional, s.Variadic = false, true
+	return s
+}
+
+// ContentChildren implements the ArgType interface.
+func (v Variadic[T]) ContentChildren() []*ContentBlockSemNode {
+	res := make([]*ContentBlockSemNode, 0)
+	for _, val := range v.Values {
+		res = append(res, val.ContentChildren()...)
+	}
+	return res
+}
+
+// UnparseArgs implements the ArgType interface.
+func (v Variadic[T]) UnparseArgs() ([]*ContentBlockSemNode, error) {
+	res := make([]*ContentBlockSemNode, 0)
+	for _, val := range v.Values {
+		args, err := val.UnparseArgs()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, args...)
+	}
+	return res, nil
+}
+
+// ParseArg implements the ArgParser interface.
+func (v *Variadic[T]) ParseArg(args []*ContentBlockSemNode, start int) (int, error) {
+	v.Values = make([]T, 0)
+	i := start
+	for i < len(args) {
+		var val T
+		p, err := argParserOf(&val)
+		if err != nil {
+			return 0, err
+		}
+		n, err := p.ParseArg(args, i)
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, fmt.Errorf("arg type %s consumed no args, so it cannot be variadic", genericSlotName(&val))
+		}
+		v.Values = append(v.Values, val)
+		i += n
+	}
+	return i - start, nil
+}
+
+// SetContentChild implements the ArgParser interface.
+func (v *Variadic[T]) SetContentChild(i int, c *ContentBlockSemNode) bool {
+	for j := range v.Values {
+		n := len(v.Values[j].ContentChildren())
+		if i < n {
+			p, err := argParserOf(&v.Values[j])
+			return err == nil && p.SetContentChild(i, c)
+		}
+		i -= n
+	}
+	return false
+}
+
+// argParserOf returns slot, which is a pointer to an ArgType, as an ArgParser, or an error if it does not implement it.
+func argParserOf(slot any) (ArgParser, error) {
+	p, ok := slot.(ArgParser)
+	if !ok {
+		return nil, fmt.Errorf("arg type %s does not implement ArgParser", genericSlotName(slot))
+	}
+	return p, nil
+}
+
+// Args1 is a semantic node with a single arg of type A, where A is one of the ArgTypes.
+// For example, Args1[Variadic[Content]] is a list of content.
+// It only partially implements the SemNode interface, as it does not implement SyntaxType.
+type Args1[A ArgType] struct {
+	A A
+}
+
+// Args2 is a semantic node with two args of types A and B, where each is one of the ArgTypes.
+// For example, Args2[Content, URL] is a captioned link, and Args2[Content, Optional[Int]] is a heading with an optional level.
+// Optional ArgTypes must come after all required ones and a Variadic ArgType must be last, otherwise registering the node returns an error.
+// It only partially implements the SemNode interface, as it does not implement SyntaxType.
+// In Registry.Schema, its args are named after the fields A and B.
+type Args2[A, B ArgType] struct {
+	A A
+	B B
+}
+
+// Args3 is like Args2, but with three args.
+type Args3[A, B, C ArgType] struct {
+	A A
+	B B
+	C C
+}
+
+// Args4 is like Args2, but with four args.
+type Args4[A, B, C, D ArgType] struct {
+	A A
+	B B
+	C C
+	D D
+}
+
+func (n *Args1[A]) slots() []genericSlot { return []genericSlot{{n.A, &n.A}} }
+
+func (n *Args2[A, B]) slots() []genericSlot { return []genericSlot{{n.A, &n.A}, {n.B, &n.B}} }
+
+func (n *Args3[A, B, C]) slots() []genericSlot {
+	return []genericSlot{{n.A, &n.A}, {n.B, &n.B}, {n.C, &n.C}}
+}
+
+func (n *Args4[A, B, C, D]) slots() []genericSlot {
+	return []genericSlot{{n.A, &n.A}, {n.B, &n.B}, {n.C, &n.C}, {n.D, &n.D}}
+}
+
+// ParseArgs implements the SemNode interface.
+func (n *Args1[A]) ParseArgs(args []*ContentBlockSemNode) error {
+	return parseGenericArgs(args, n.slots())
+}
+
+// Children implements the SemNode interface.
+func (n *Args1[A]) Children() []SemNode { return genericChildren(n.slots()) }
+
+// SetChild implements the MutableSemNode interface.
+func (n *Args1[A]) SetChild(i int, c SemNode) error { return setGenericChild(i, c, n.slots()) }
+
+// Unparse implements the UnparsableSemNode interface.
+func (n *Args1[A]) Unparse() ([]*ContentBlockSemNode, error) { return unparseGeneric(n.slots()) }
+
+// ParseArgs implements the SemNode interface.
+func (n *Args2[A, B]) ParseArgs(args []*ContentBlockSemNode) error {
+	return parseGenericArgs(args, n.slots())
+}
+
+// Children implements the SemNode interface.
+func (n *Args2[A, B]) Children() []SemNode { return genericChildren(n.slots()) }
+
+// SetChild implements the MutableSemNode interface.
+func (n *Args2[A, B]) SetChild(i int, c SemNode) error { return setGenericChild(i, c, n.slots()) }
+
+// Unparse implements the UnparsableSemNode interface.
+func (n *Args2[A, B]) Unparse() ([]*ContentBlockSemNode, error) { return unparseGeneric(n.slots()) }
+
+// ParseArgs implements the SemNode interface.
+func (n *Args3[A, B, C]) ParseArgs(args []*ContentBlockSemNode) error {
+	return parseGenericArgs(args, n.slots())
+}
+
+// Children implements the SemNode interface.
+func (n *Args3[A, B, C]) Children() []SemNode { return genericChildren(n.slots()) }
+
+// SetChild implements the MutableSemNode interface.
+func (n *Args3[A, B, C]) SetChild(i int, c SemNode) error { return setGenericChild(i, c, n.slots()) }
+
+// Unparse implements the UnparsableSemNode interface.
+func (n *Args3[A, B, C]) Unparse() ([]*ContentBlockSemNode, error) { return unparseGeneric(n.slots()) }
+
+// ParseArgs implements the SemNode interface.
+func (n *Args4[A, B, C, D]) ParseArgs(args []*ContentBlockSemNode) error {
+	return parseGenericArgs(args, n.slots())
+}
+
+// Children implements the SemNode interface.
+func (n *Args4[A, B, C, D]) Children() []SemNode { return genericChildren(n.slots()) }
+
+// SetChild implements the MutableSemNode interface.
+func (n *Args4[A, B, C, D]) SetChild(i int, c SemNode) error {
+	return setGenericChild(i, c, n.slots())
+}
+
+// Unparse implements the UnparsableSemNode interface.
+func (n *Args4[A, B, C, D]) Unparse() ([]*ContentBlockSemNode, error) {
+	return unparseGeneric(n.slots())
+}
+
+// genericSemNode is implemented by Args1, Args2, Args3 and Args4, so that the order of their ArgTypes can be checked when they are registered,
+// and their args can be described in Registry.Schema.
+type genericSemNode interface {
+	// slots returns each arg, in order.
+	slots() []genericSlot
+}
+
+// genericSlot is a single arg of a generic node.
+type genericSlot struct {
+	// arg is the current value of the arg.
+	arg ArgType
+	// ptr points to the field that holds the arg, which is used to parse it.
+	ptr any
+}
+
+// genericSlotNames are the names of the args of generic nodes, which are the names of their fields.
+var genericSlotNames = []string{"A", "B", "C", "D"}
+
+// genericArgSchemas describes each arg of a generic node.
+func genericArgSchemas(g genericSemNode) []ArgSchema {
+	slots := g.slots()
+	res := make([]ArgSchema, len(slots))
+	for i, s := range slots {
+		res[i] = s.arg.Schema()
+		res[i].Name = genericSlotNames[i]
+	}
+	return res
+}
+
+// checkGenericSlots returns an error if the slots are not in an order that can be parsed: required args first, then optional args,
+// then at most one variadic arg. Otherwise, it would not be possible to tell which slot each arg is for.
+// Each slot must also implement ArgParser.
+func checkGenericSlots(slots []genericSlot) error {
+	optional, variadic := false, false
+	for i, s := range slots {
+		if _, err := argParserOf(s.ptr); err != nil {
+			return err
+		}
+		if variadic {
+			return fmt.Errorf("arg type %d (%s) comes after a Variadic arg type, which must be last", i, genericSlotName(s.ptr))
+		}
+		min, max := s.arg.Arity()
+		switch {
+		case max < 0:
+			variadic = true
+		case min < max:
+			optional = true
+		case optional:
+			return fmt.Errorf("arg type %d (%s) is required, but comes after an Optional arg type", i, genericSlotName(s.ptr))
+		}
+	}
+	return nil
+}
+
+// genericSlotName returns the name of the ArgType of a slot, without the package, such as Optional[Text].
+func genericSlotName(slot any) string {
+	t := reflect.TypeOf(slot).Elem()
+	// Type arguments are written with their full package path, and the type itself with just the package name
+	name := strings.ReplaceAll(t.String(), t.PkgPath()+".", "")
+	return strings.ReplaceAll(name, "obtext.", "")
+}
+
+// genericArity returns the minimum and maximum number of args of all of the slots together, where a maximum of -1 means any number.
+func genericArity(slots []genericSlot) (int, int) {
+	min, max := 0, 0
+	for _, s := range slots {
+		smin, smax := s.arg.Arity()
+		min += smin
+		if max >= 0 {
+			if smax < 0 {
+				max = -1
+			} else {
+				max += smax
+			}
+		}
+	}
+	return min, max
+}
+
+// parseGenericArgs checks the number of args against the arity of the slots, then parses them in order.
+func parseGenericArgs(args []*ContentBlockSemNode, slots []genericSlot) error {
+	if err := checkGenericSlots(slots); err != nil {
+		return err
+	}
+	min, max := genericArity(slots)
+	if err := checkArgCount(args, min, max); err != nil {
+		return err
+	}
+	i := 0
+	for _, s := range slots {
+		n, err := s.ptr.(ArgParser).ParseArg(args, i)
+		if err != nil {
+			return err
+		}
+		i += n
+	}
+	return nil
+}
+
+func genericChildren(slots []genericSlot) []SemNode {
+	res := make([]SemNode, 0, len(slots))
+	for _, s := range slots {
+		for _, c := range s.arg.ContentChildren() {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// setGenericChild replaces the i-th child with c.
+func setGenericChild(i int, c SemNode, slots []genericSlot) error {
+	block, ok := c.(*ContentBlockSemNode)
+	if !ok {
+		return fmt.Errorf("child %d must be a content block, got %T", i, c)
+	}
+	for _, s := range slots {
+		n := len(s.arg.ContentChildren())
+		if i < n {
+			if p, err := argParserOf(s.ptr); err != nil || !p.SetContentChild(i, block) {
+				return fmt.Errorf("child %d cannot be replaced", i)
+			}
+			return nil
+		}
+		i -= n
+	}
+	return fmt.Errorf("child index out of range")
+}
+
+func unparseGeneric(slots []genericSlot) ([]*ContentBlockSemNode, error) {
+	res := make([]*ContentBlockSemNode, 0, len(slots))
+	for _, s := range slots {
+		args, err := s.arg.UnparseArgs()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, args...)
+	}
+	return res, nil
+}
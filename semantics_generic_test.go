@@ -0,0 +1,241 @@
+package obtext
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testHeading2 struct {
+	Args3[Content, Optional[Int], Optional[URL]]
+}
+
+func (*testHeading2) SyntaxType() string { return "h" }
+
+type testGallery struct {
+	Args2[Text, Variadic[Content]]
+}
+
+func (*testGallery) SyntaxType() string { return "gallery" }
+
+type testBadOptional struct {
+	Args2[Optional[Text], Content]
+}
+
+func (*testBadOptional) SyntaxType() string { return "bad" }
+
+type testBadVariadic struct {
+	Args3[Content, Variadic[Text], Optional[Text]]
+}
+
+func (*testBadVariadic) SyntaxType() string { return "bad" }
+
+// testPoint is an ArgType that is not built in, which takes two int args.
+type testPoint struct {
+	X, Y int
+}
+
+func (testPoint) Arity() (int, int) { return 2, 2 }
+
+func (testPoint) Schema() ArgSchema { return ArgSchema{Kind: "point", Description: "two integers"} }
+
+func (testPoint) ContentChildren() []*ContentBlockSemNode { return nil }
+
+func (p testPoint) UnparseArgs() ([]*ContentBlockSemNode, error) {
+	return (&Args2[Int, Int]{Int{p.X}, Int{p.Y}}).Unparse()
+}
+
+func (p *testPoint) ParseArg(args []*ContentBlockSemNode, start int) (int, error) {
+	var xy Args2[Int, Int]
+	if err := xy.ParseArgs(args[start : start+2]); err != nil {
+		return 0, err
+	}
+	p.X, p.Y = xy.A.Value, xy.B.Value
+	return 2, nil
+}
+
+func (p *testPoint) SetContentChild(int, *ContentBlockSemNode) bool { return false }
+
+type testShape struct {
+	Args2[Text, Variadic[testPoint]]
+}
+
+func (*testShape) SyntaxType() string { return "shape" }
+
+func TestGenericArgs(t *testing.T) {
+	sem, err := ParseSem(mustParseSyn(t, "@h{Title}{3}{https://example.com}"), []SemNode{&testHeading2{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sem.(*testHeading2)
+	level, ok := h.B.Get()
+	if textOf(h.A.Value) != "Title" || !ok || level.Value != 3 || !h.C.Set || h.C.Value.Value.Host != "example.com" {
+		t.Errorf("got %+v", h)
+	}
+	sem, err = ParseSem(mustParseSyn(t, "@h{Title}"), []SemNode{&testHeading2{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := sem.(*testHeading2); h.B.Set || h.C.Set {
+		t.Errorf("optional args were set when missing: %+v", h)
+	}
+}
+
+func TestGenericArgsErrors(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected string
+	}{
+		{"@h", "@h must have between 1 and 3 arguments, got 0"},
+		{"@h{a}{b}{c}{d}", "@h must have between 1 and 3 arguments, got 4"},
+		{"@h{a}{three}", `@h arg 1 must be an integer, got "three"`},
+		{"@gallery{@h{a}}", "@gallery arg 0 must be text, got object @h"},
+	}
+	for _, c := range cases {
+		_, err := ParseSem(mustParseSyn(t, c.src), []SemNode{&testHeading2{}, &testGallery{}})
+		var semErr *SemError
+		if !errors.As(err, &semErr) || semErr.Err.Error() != c.expected {
+			t.Errorf("%s: got %v, want %q", c.src, err, c.expected)
+		}
+	}
+}
+
+func TestGenericVariadic(t *testing.T) {
+	sem, err := ParseSem(mustParseSyn(t, "@gallery{Title}{a}{b}{c}"), []SemNode{&testGallery{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := sem.(*testGallery)
+	if g.A.Value != "Title" || len(g.B.Values) != 3 || len(g.Children()) != 3 {
+		t.Errorf("got %+v", g)
+	}
+	replacement := textBlock("x")
+	if err := g.SetChild(1, replacement); err != nil || g.B.Values[1].Value != replacement {
+		t.Errorf("failed to replace a variadic child: %v", err)
+	}
+	if err := g.SetChild(3, replacement); err == nil {
+		t.Errorf("expected an error for an index out of range")
+	}
+	unparsed, err := UnparseSem(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatSynSource(unparsed); got != "@gallery{Title}{a}{x}{c}" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestGenericArgTypeOrder(t *testing.T) {
+	cases := []struct {
+		node     SemNode
+		expected string
+	}{
+		{&testBadOptional{}, "cannot register @bad: arg type 1 (Content) is required, but comes after an Optional arg type"},
+		{&testBadVariadic{}, "cannot register @bad: arg type 2 (Optional[Text]) comes after a Variadic arg type, which must be last"},
+	}
+	for _, c := range cases {
+		_, err := NewRegistry(c.node)
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("got %v, want %q", err, c.expected)
+		}
+		reg := mustNewRegistry(t)
+		if err := reg.RegisterFactory(func() SemNode { return c.node }); err == nil {
+			t.Errorf("expected an error registering %T with a factory", c.node)
+		}
+		if _, err := ParseSem(mustParseSyn(t, "@bad{x}"), []SemNode{c.node}); err == nil {
+			t.Errorf("expected an error parsing with %T", c.node)
+		}
+	}
+}
+
+func TestGenericArgsNeverPanic(t *testing.T) {
+	// Nodes with invalid orders can still have ParseArgs called directly, which must return an error rather than panic
+	nodes := []SemNode{&testHeading2{}, &testGallery{}, &testBadOptional{}, &testBadVariadic{}}
+	for _, n := range nodes {
+		for count := 0; count < 6; count++ {
+			args := make([]*ContentBlockSemNode, count)
+			for i := range args {
+				args[i] = textBlock(strings.Repeat("1", i+1))
+			}
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("%T with %d args panicked: %v", n, count, r)
+					}
+				}()
+				n.ParseArgs(args)
+			}()
+		}
+	}
+	if err := (&testBadOptional{}).ParseArgs([]*ContentBlockSemNode{textBlock("x")}); err == nil {
+		t.Errorf("expected an error for arg types in an invalid order")
+	}
+}
+
+func TestGenericCustomArgType(t *testing.T) {
+	sem, err := ParseSem(mustParseSyn(t, "@shape{line}{1}{2}{3}{4}"), []SemNode{&testShape{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points := sem.(*testShape).B.Values; !reflect.DeepEqual(points, []testPoint{{1, 2}, {3, 4}}) {
+		t.Errorf("got %+v", points)
+	}
+	unparsed, err := UnparseSem(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatSynSource(unparsed); got != "@shape{line}{1}{2}{3}{4}" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestGenericCastValues(t *testing.T) {
+	syn := mustParseSyn(t, "@h{Title}{3}{https://example.com}")
+	if _, err := ParseSem(syn, []SemNode{&testHeading2{}}); err != nil {
+		t.Fatal(err)
+	}
+	if syn.Args[0].CastValue != nil || syn.Args[1].CastValue != 3 || syn.Args[2].CastValue.(*url.URL).Host != "example.com" {
+		t.Errorf("got cast values %v, %v, %v", syn.Args[0].CastValue, syn.Args[1].CastValue, syn.Args[2].CastValue)
+	}
+	// A value that was already cast is used rather than the text
+	args := []*ContentBlockSemNode{textBlock("Title"), {Elements: []SemNode{&TextSemNode{Text: "3"}}, CastValue: 4}}
+	var h testHeading2
+	if err := h.ParseArgs(args); err != nil || h.B.Value.Value != 4 {
+		t.Errorf("got %+v, %v", h, err)
+	}
+}
+
+func TestGenericSchema(t *testing.T) {
+	reg := mustNewRegistry(t, &testHeading2{}, &testGallery{}, &testShape{})
+	schemas := schemaByName(t, reg)
+	h := schemas["h"]
+	expected := []ArgSchema{
+		{Name: "A", Kind: "content"},
+		{Name: "B", Kind: "int", Description: "must be an integer", Optional: true},
+		{Name: "C", Kind: "url", Description: "must be a URL", Optional: true},
+	}
+	if !reflect.DeepEqual(h.Args, expected) || h.MinArgs != 1 || h.MaxArgs != 3 {
+		t.Errorf("got %+v", h)
+	}
+	if got := h.Usage(); got != "@h{A}[{B}][{C}]" {
+		t.Errorf("got usage %s", got)
+	}
+	if g := schemas["gallery"]; g.MinArgs != 1 || g.MaxArgs != -1 || !g.Args[1].Variadic || g.Usage() != "@gallery{A}{B}..." {
+		t.Errorf("got %+v", g)
+	}
+	// The arg count comes from the arity of each ArgType, which is not always one
+	if s := schemas["shape"]; s.MinArgs != 1 || s.MaxArgs != -1 || s.Args[1].Kind != "point" {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestGenericUnparseNilURL(t *testing.T) {
+	h := &testHeading2{}
+	h.A.Value = textBlock("Title")
+	h.C.Set = true
+	if _, err := UnparseSem(h); err == nil {
+		t.Errorf("expected an error unparsing a URL with no value")
+	}
+}
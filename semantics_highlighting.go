@@ -0,0 +1,149 @@
+package obtext
+
+//go:generate go run ./language_support/generate.go
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The regexes shared by the generated syntax definitions, which must match what the syntax parser accepts.
+const (
+	highlightNameChars = `a-zA-Z0-9_`
+	highlightName      = `[a-zA-Z0-9_]+(\.[a-zA-Z0-9_]+)*`
+	highlightEscape    = `\\[@{}]`
+)
+
+// NanoSyntax generates a nano syntax definition (nanorc) for the objects in the registry.
+// Known objects, unknown objects, escapes and verbatim args (the args of objects that only take text, such as file paths) are all highlighted differently.
+// As nanorc is based on regexes, an object is only recognised as known if it is followed by something other than a name character.
+func (r *Registry) NanoSyntax() string {
+	known, verbatim := r.highlightNames()
+	out := "## Syntax highlighting for Obtext files\n"
+	out += "## Generated by Registry.NanoSyntax, do not edit by hand\n"
+	out += "syntax obt \"\\.obt$\"\n\n"
+	out += "# Make all @objects red, as they are unknown unless matched below\n"
+	out += fmt.Sprintf("color brightred \"@%s\"\n", highlightName)
+	if len(known) > 0 {
+		out += "# Make known @objects purple\n"
+		out += fmt.Sprintf("color magenta \"@(%s)([^%s.]|$)\"\n", strings.Join(known, "|"), highlightNameChars)
+	}
+	if len(verbatim) > 0 {
+		out += "# Make the args of objects that only take text green\n"
+		out += fmt.Sprintf("color green \"@(%s)([[:space:]]*\\{([^\\\\}]|\\\\.)*\\})+\"\n", strings.Join(verbatim, "|"))
+		out += fmt.Sprintf("color magenta \"@(%s)\"\n", strings.Join(verbatim, "|"))
+	}
+	out += "\n# Color brackets yellow\n"
+	out += "color yellow \"[{}]\"\n"
+	out += "\n# Remove the color from escaped objects, and make escapes cyan\n"
+	out += fmt.Sprintf("color white \"\\\\@%s\"\n", highlightName)
+	out += fmt.Sprintf("color cyan \"%s\"\n", highlightEscape)
+	return out
+}
+
+// textMateGrammar is the JSON structure of a TextMate grammar, as used by VSCode.
+type textMateGrammar struct {
+	Schema     string                     `json:"$schema"`
+	Name       string                     `json:"name"`
+	Patterns   []textMatePattern          `json:"patterns"`
+	Repository map[string]textMatePattern `json:"repository"`
+	ScopeName  string                     `json:"scopeName"`
+}
+
+// textMatePattern is a single rule of a TextMate grammar.
+type textMatePattern struct {
+	Include       string                     `json:"include,omitempty"`
+	Name          string                     `json:"name,omitempty"`
+	Match         string                     `json:"match,omitempty"`
+	Begin         string                     `json:"begin,omitempty"`
+	End           string                     `json:"end,omitempty"`
+	BeginCaptures map[string]textMatePattern `json:"beginCaptures,omitempty"`
+	EndCaptures   map[string]textMatePattern `json:"endCaptures,omitempty"`
+	Patterns      []textMatePattern          `json:"patterns,omitempty"`
+}
+
+// TextMateGrammar generates a TextMate grammar (such as obtext.tmLanguage.json for VSCode) for the objects in the registry.
+// Known objects, unknown objects, escapes and verbatim args (the args of objects that only take text, such as file paths) are all given different scopes.
+func (r *Registry) TextMateGrammar() ([]byte, error) {
+	known, verbatim := r.highlightNames()
+	include := func(name string) textMatePattern { return textMatePattern{Include: "#" + name} }
+	brace := map[string]textMatePattern{"0": {Name: "punctuation.section.braces.obtext"}}
+	repo := map[string]textMatePattern{
+		"escapes": {Name: "constant.character.escape.obtext", Match: highlightEscape},
+		"unknown": {Name: "invalid.illegal.unknown.obtext", Match: `(?<!\\)@` + highlightName},
+		"braces":  {Name: "punctuation.section.braces.obtext", Match: `[{}]`},
+	}
+	patterns := []textMatePattern{include("escapes")}
+	if len(verbatim) > 0 {
+		repo["verbatim"] = textMatePattern{
+			Begin:         fmt.Sprintf(`(?<!\\)@(?:%s)(?![%s.])(?=\s*\{)`, strings.Join(verbatim, "|"), highlightNameChars),
+			BeginCaptures: map[string]textMatePattern{"0": {Name: "keyword.control.obtext"}},
+			End:           `(?<=\})(?!\s*\{)`,
+			Patterns: []textMatePattern{{
+				Name:          "string.unquoted.obtext",
+				Begin:         `\{`,
+				BeginCaptures: brace,
+				End:           `\}`,
+				EndCaptures:   brace,
+				Patterns:      []textMatePattern{include("escapes")},
+			}},
+		}
+		patterns = append(patterns, include("verbatim"))
+	}
+	if len(known) > 0 {
+		repo["keywords"] = textMatePattern{
+			Name:  "keyword.control.obtext",
+			Match: fmt.Sprintf(`(?<!\\)@(?:%s)(?![%s.])`, strings.Join(known, "|"), highlightNameChars),
+		}
+		patterns = append(patterns, include("keywords"))
+	}
+	patterns = append(patterns, include("unknown"), include("braces"))
+	// The regexes contain '<', which should not be escaped as it would make the grammar harder to read
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	err := enc.Encode(textMateGrammar{
+		Schema:     "https://raw.githubusercontent.com/martinring/tmlanguage/master/tmlanguage.json",
+		Name:       "obtext",
+		Patterns:   patterns,
+		Repository: repo,
+		ScopeName:  "source.obt",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// highlightNames returns the escaped names (including aliases) of all known objects, and of those that are verbatim, for use in regexes.
+// An object is verbatim if all of its args may only contain text, so they are highlighted as strings rather than as obtext.
+// Longer names come first so that they are preferred over any names they start with.
+func (r *Registry) highlightNames() (known, verbatim []string) {
+	verbatimTypes := make(map[string]bool)
+	for _, s := range r.Schema() {
+		verbatimTypes[s.Name] = len(s.Args) > 0
+		for _, a := range s.Args {
+			if a.Kind == "content" && !(len(a.Allow) == 1 && a.Allow[0] == TextContent) {
+				verbatimTypes[s.Name] = false
+			}
+		}
+	}
+	for _, name := range r.Names() {
+		quoted := regexp.QuoteMeta(name)
+		known = append(known, quoted)
+		if verbatimTypes[r.canonical(name)] {
+			verbatim = append(verbatim, quoted)
+		}
+	}
+	byLength := func(names []string) {
+		sort.SliceStable(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	}
+	byLength(known)
+	byLength(verbatim)
+	return known, verbatim
+}
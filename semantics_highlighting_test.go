@@ -0,0 +1,130 @@
+package obtext
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// highlightRegistry returns a registry with a verbatim object (@code), an object whose content may only be text (@title),
+// normal objects, an object in a pack and an alias.
+func highlightRegistry(t *testing.T) *Registry {
+	t.Helper()
+	reg := mustNewRegistry(t, &testCode{}, &testTitle{}, &testBold{}, &testFrozen{})
+	if err := reg.RegisterPack("ns", &testEm{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Alias("b", "bold"); err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func TestHighlightNames(t *testing.T) {
+	known, verbatim := highlightRegistry(t).highlightNames()
+	expected := []string{"frozen", `ns\.em`, "title", "bold", "code", "b"}
+	if !reflect.DeepEqual(known, expected) {
+		t.Errorf("got known %v, want %v", known, expected)
+	}
+	if !reflect.DeepEqual(verbatim, []string{"title", "code"}) {
+		t.Errorf("got verbatim %v", verbatim)
+	}
+}
+
+func TestHighlightRegexesMatchSyntax(t *testing.T) {
+	// The shared regexes must agree with the syntax parser about names and escapes
+	name := regexp.MustCompile(`^@` + highlightName + `$`)
+	for _, src := range []string{"@a", "@a_1", "@ns.em", "@a.b.c"} {
+		syn, err := ParseSynString(src)
+		if err != nil || !name.MatchString(src) || "@"+syn.Type != src {
+			t.Errorf("%s: the name regex does not agree with the parser (%v)", src, err)
+		}
+	}
+	escape := regexp.MustCompile(`^` + highlightEscape + `$`)
+	for _, e := range []string{`\@`, `\{`, `\}`} {
+		if !escape.MatchString(e) {
+			t.Errorf("%s is not matched as an escape", e)
+		}
+	}
+	for _, e := range []string{`\x`, `\\`} {
+		if escape.MatchString(e) {
+			t.Errorf("%s is matched as an escape, but it is text", e)
+		}
+	}
+}
+
+func TestNanoSyntax(t *testing.T) {
+	nano := highlightRegistry(t).NanoSyntax()
+	if !strings.HasPrefix(nano, "## Syntax highlighting for Obtext files\n") || !strings.Contains(nano, `syntax obt "\.obt$"`) {
+		t.Errorf("missing header:\n%s", nano)
+	}
+	// Every color rule must be a valid regex, and known objects must only match whole names
+	var known *regexp.Regexp
+	for _, line := range strings.Split(nano, "\n") {
+		if !strings.HasPrefix(line, "color ") {
+			continue
+		}
+		pattern := line[strings.Index(line, `"`)+1 : len(line)-1]
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			t.Errorf("invalid regex %q: %v", pattern, err)
+			continue
+		}
+		if strings.HasPrefix(line, "color magenta") && known == nil {
+			known = re
+		}
+	}
+	if known == nil {
+		t.Fatalf("no rule for known objects:\n%s", nano)
+	}
+	for src, want := range map[string]bool{"@bold{x}": true, "@b ": true, "@ns.em{x}": true, "@code": true, "@bolder": false, "@ns.emx": false, "@bold.x": false, "@unknown": false} {
+		if got := known.MatchString(src); got != want {
+			t.Errorf("%s: matched as a known object: %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestNanoSyntaxEmptyRegistry(t *testing.T) {
+	nano := mustNewRegistry(t).NanoSyntax()
+	if strings.Contains(nano, "magenta") || strings.Contains(nano, "green") {
+		t.Errorf("rules for known objects were generated for an empty registry:\n%s", nano)
+	}
+}
+
+func TestTextMateGrammar(t *testing.T) {
+	data, err := highlightRegistry(t).TextMateGrammar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `\u003c`) {
+		t.Errorf("'<' was escaped in the grammar")
+	}
+	var grammar textMateGrammar
+	if err := json.Unmarshal(data, &grammar); err != nil {
+		t.Fatalf("the grammar is not valid JSON: %v", err)
+	}
+	if grammar.ScopeName != "source.obt" || grammar.Name != "obtext" {
+		t.Errorf("got %+v", grammar)
+	}
+	var includes []string
+	for _, p := range grammar.Patterns {
+		includes = append(includes, p.Include)
+	}
+	// Escapes come first, and verbatim objects are matched before other known objects
+	if !reflect.DeepEqual(includes, []string{"#escapes", "#verbatim", "#keywords", "#unknown", "#braces"}) {
+		t.Errorf("got patterns %v", includes)
+	}
+	for _, include := range includes {
+		if _, ok := grammar.Repository[strings.TrimPrefix(include, "#")]; !ok {
+			t.Errorf("%s is not in the repository", include)
+		}
+	}
+	if kw := grammar.Repository["keywords"].Match; !strings.Contains(kw, `frozen|ns\.em|title|bold|code|b`) {
+		t.Errorf("got keywords %q", kw)
+	}
+	if v := grammar.Repository["verbatim"].Begin; !strings.Contains(v, "(?:title|code)") {
+		t.Errorf("got verbatim %q", v)
+	}
+}
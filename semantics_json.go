@@ -0,0 +1,147 @@
+package obtext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// jsonElement is the JSON form of a single object or piece of text.
+// Objects are {"type": "para", "args": [[...], ...]} where each arg is a list of elements, and text is {"text": "..."}.
+type jsonElement struct {
+	Type string          `json:"type,omitempty"`
+	Args [][]jsonElement `json:"args,omitempty"`
+	Text *string         `json:"text,omitempty"`
+}
+
+// MarshalSemJSON encodes a semantic tree as JSON, so it can be cached or sent to a client which decodes it with UnmarshalSemJSON.
+// Each object is stored by its SyntaxType along with its args, so every node must be unparsable (see UnparseSem).
+// Nodes that were registered under another name, such as in a pack, should be encoded with Registry.MarshalSemJSON.
+// If the root is a ContentBlockSemNode, such as when the root object was unwrapped (see WithUnknownObjects), it is stored as a list of its elements.
+func MarshalSemJSON(n SemNode) ([]byte, error) {
+	return marshalSemJSON(unparser{}, n)
+}
+
+// MarshalSemJSON is like the package level MarshalSemJSON, but stores each object by the name that its type is registered under, see Registry.UnparseSem.
+func (r *Registry) MarshalSemJSON(n SemNode) ([]byte, error) {
+	return marshalSemJSON(r.unparser(), n)
+}
+
+func marshalSemJSON(u unparser, n SemNode) ([]byte, error) {
+	if c, ok := n.(*ContentBlockSemNode); ok {
+		arg, err := u.arg(c)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(toJSONElements(arg.Elements))
+	}
+	obj, err := u.root(n)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(toJSONElement(obj))
+}
+
+// UnmarshalSemJSON decodes a semantic tree that was encoded with MarshalSemJSON, using the same semantics that were passed to ParseSem.
+// The args of each object are parsed again by its node, so the result is the same as if the original source had been parsed,
+// apart from source positions which are not stored. The same options should be given as when it was first parsed,
+// for example WithUnknownObjects(UnknownKeep) if the tree contains UnknownSemNodes.
+// The exception is WithPasses: the tree is encoded as it was after its passes ran, so passes that change the tree must not be given again,
+// or they would be applied twice. Values that are not stored in the args of a node, such as those set by passes or by Resolve, are not encoded.
+// Resolve is called again when decoding, so passes that it relies on and that only compute values, rather than change the tree, should be given again.
+func UnmarshalSemJSON(data []byte, semantics []SemNode, opts ...SemOption) (SemNode, error) {
+	syn, err := unmarshalSynJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSem(syn, semantics, opts...)
+}
+
+// UnmarshalSemJSON is like the package level UnmarshalSemJSON, but uses the nodes in the registry.
+func (r *Registry) UnmarshalSemJSON(data []byte, opts ...SemOption) (SemNode, error) {
+	syn, err := unmarshalSynJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.Parse(syn, opts...)
+}
+
+// unmarshalSynJSON decodes the syntax of a semantic tree, which is an *ObjectSynNode, or an *ArgSynNode if the root was a content block.
+func unmarshalSynJSON(data []byte) (any, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var elements []jsonElement
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return nil, err
+		}
+		return fromJSONElements(elements)
+	}
+	var root jsonElement
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	el, err := fromJSONElement(root)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := el.(*ObjectSynNode)
+	if !ok {
+		return nil, errors.New("the root of a semantic tree must be an object or a list of elements")
+	}
+	return obj, nil
+}
+
+func toJSONElement(e SynElement) jsonElement {
+	switch e := e.(type) {
+	case *TextSynNode:
+		text := e.Value
+		return jsonElement{Text: &text}
+	case *ObjectSynNode:
+		je := jsonElement{Type: e.Type, Args: make([][]jsonElement, len(e.Args))}
+		for i, a := range e.Args {
+			je.Args[i] = toJSONElements(a.Elements)
+		}
+		return je
+	}
+	panic("unknown type")
+}
+
+func toJSONElements(elements []SynElement) []jsonElement {
+	res := make([]jsonElement, len(elements))
+	for i, e := range elements {
+		res[i] = toJSONElement(e)
+	}
+	return res
+}
+
+func fromJSONElement(je jsonElement) (SynElement, error) {
+	if je.Text != nil {
+		if je.Type != "" || je.Args != nil {
+			return nil, errors.New("a JSON element must not have both text and a type")
+		}
+		return &TextSynNode{Value: *je.Text}, nil
+	}
+	if je.Type == "" {
+		return nil, errors.New("a JSON element must have either text or a type")
+	}
+	obj := &ObjectSynNode{Type: je.Type, Args: make([]*ArgSynNode, len(je.Args))}
+	for i, a := range je.Args {
+		arg, err := fromJSONElements(a)
+		if err != nil {
+			return nil, err
+		}
+		obj.Args[i] = arg
+	}
+	return obj, nil
+}
+
+func fromJSONElements(elements []jsonElement) (*ArgSynNode, error) {
+	arg := &ArgSynNode{Elements: make([]SynElement, len(elements))}
+	for i, je := range elements {
+		el, err := fromJSONElement(je)
+		if err != nil {
+			return nil, err
+		}
+		arg.Elements[i] = el
+	}
+	return arg, nil
+}
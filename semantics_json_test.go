@@ -0,0 +1,123 @@
+package obtext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSemJSONRoundTrip(t *testing.T) {
+	src := "@doc{Some @bold{bold} text with @link{https://example.com}{a link}, @list{a}{b} and @code{go}{x := 1}.}"
+	sem := mustParseTestSem(t, src)
+	data, err := MarshalSemJSON(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := UnmarshalSemJSON(data, testSemantics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sem, decoded) {
+		t.Errorf("the decoded tree is different from the original")
+	}
+}
+
+func TestSemJSONPasses(t *testing.T) {
+	addNote := func(ctx *PassContext) error {
+		ctx.Root = &ContentBlockSemNode{Elements: []SemNode{ctx.Root, &TextSemNode{Text: "note"}}}
+		return nil
+	}
+	syn := mustParseSyn(t, "@doc{@ref{a} @bold{a}}")
+	sem, err := ParseSem(syn, passSemantics(), WithPasses(collectFigures, addNote))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalSemJSON(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The note is already in the encoded tree, but the figures are not, so only the pass that computes them is given again
+	decoded, err := UnmarshalSemJSON(data, passSemantics(), WithPasses(collectFigures))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sem, decoded) {
+		t.Errorf("the decoded tree is different from the original")
+	}
+	decoded, err = UnmarshalSemJSON(data, passSemantics(), WithPasses(collectFigures, addNote))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(sem, decoded) || len(decoded.(*ContentBlockSemNode).Elements) != 2 || len(decoded.Children()[0].(*ContentBlockSemNode).Elements) != 2 {
+		t.Errorf("expected the note to be added twice when the pass is given again")
+	}
+	if _, err := UnmarshalSemJSON(data, passSemantics()); err == nil {
+		t.Errorf("expected an error resolving the reference without the figures")
+	}
+}
+
+func TestSemJSONFormat(t *testing.T) {
+	data, err := MarshalSemJSON(mustParseTestSem(t, "@doc{a @bold{b}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"type":"doc","args":[[{"text":"a "},{"type":"bold","args":[[{"text":"b"}]]}]]}`
+	if string(data) != expected {
+		t.Errorf("got %s, want %s", data, expected)
+	}
+}
+
+func TestRegistrySemJSONUsesRegisteredNames(t *testing.T) {
+	reg := mustNewRegistry(t, &testDoc{})
+	if err := reg.RegisterPack("gallery", &testGrid{}); err != nil {
+		t.Fatal(err)
+	}
+	sem, err := reg.Parse(mustParseSyn(t, "@doc{@gallery.grid{x}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := reg.MarshalSemJSON(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := reg.UnmarshalSemJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sem, decoded) {
+		t.Errorf("the decoded tree is different from the original: %s", data)
+	}
+}
+
+func TestSemJSONContentBlockRoot(t *testing.T) {
+	sem := mustParseTestSem(t, "@foo{a @bold{b}}", WithUnknownObjects(UnknownUnwrap))
+	data, err := MarshalSemJSON(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `[{"text":"a "},{"type":"bold","args":[[{"text":"b"}]]}]`; string(data) != expected {
+		t.Errorf("got %s, want %s", data, expected)
+	}
+	decoded, err := UnmarshalSemJSON(data, testSemantics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sem, decoded) {
+		t.Errorf("got %#v, want %#v", decoded, sem)
+	}
+}
+
+func TestSemJSONErrors(t *testing.T) {
+	invalid := []string{
+		`not json`,
+		`{"text":"root text"}`,
+		`{"type":"doc","args":[[{"text":"a","type":"bold"}]]}`,
+		`{"type":"doc","args":[[{}]]}`,
+		`[{}]`,
+		`{"type":"missing"}`,
+	}
+	for _, data := range invalid {
+		if _, err := UnmarshalSemJSON([]byte(data), testSemantics()); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+}
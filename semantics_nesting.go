@@ -0,0 +1,250 @@
+package obtext
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// TextContent is used in a ContentModel to refer to plain text.
+	TextContent = "#text"
+	// RootParent is used in ContentModel.Parents to allow a node to be the root of the document.
+	RootParent = "#root"
+)
+
+// ContentModelSemNode is an optional interface that a SemNode can implement to restrict where it may be used and what it may contain.
+// ParseSem enforces the content model of every node that implements it, returning a NestingError for any violations.
+// Nodes that do not implement it may be used anywhere that objects are allowed, but not in args that only allow text.
+type ContentModelSemNode interface {
+	SemNode
+	// ContentModel returns the nesting rules of the node.
+	ContentModel() ContentModel
+}
+
+// ContentModel describes the nesting rules of a node.
+type ContentModel struct {
+	// Categories are the content categories that the node belongs to, such as 'block' or 'inline'.
+	Categories []string
+	// Parents are the syntax types that the node may be directly inside of, which may include RootParent.
+	// If empty, the node may be inside anything.
+	Parents []string
+	// Args are the rules for each argument of the node.
+	// If there are more arguments than rules, the last rule is used for the rest. If empty, the arguments may contain anything.
+	Args []ArgContentModel
+}
+
+// ArgContentModel describes what a single argument of a node may contain.
+type ArgContentModel struct {
+	// Allow is a list of categories and syntax types (and possibly TextContent) that may be used in the argument.
+	// If nil, anything may be used.
+	Allow []string
+	// MaxOccurs limits the number of times a category or syntax type (or TextContent) may be used in the argument.
+	MaxOccurs map[string]int
+}
+
+// forArg returns the rules for the i-th argument, or nil if there are none.
+func (m ContentModel) forArg(i int) *ArgContentModel {
+	if len(m.Args) == 0 {
+		return nil
+	}
+	if i >= len(m.Args) {
+		return &m.Args[len(m.Args)-1]
+	}
+	return &m.Args[i]
+}
+
+// NestingErrorKind is the kind of content model rule that was broken.
+type NestingErrorKind int
+
+const (
+	// NotAllowed means that the child is not allowed in the argument of the parent.
+	NotAllowed NestingErrorKind = iota
+	// WrongParent means that the child requires a different parent.
+	WrongParent
+	// TooMany means that the child was used more times than allowed in the argument of the parent.
+	TooMany
+)
+
+// NestingError is used when an object or text breaks the content model of itself or its parent.
+type NestingError struct {
+	Kind NestingErrorKind
+	// Parent is the syntax type of the parent, or RootParent if the child is the root.
+	Parent string
+	// Arg is the index of the argument of the parent that the child is in, or -1 if the child is the root.
+	Arg int
+	// Child is the syntax type of the child, or TextContent.
+	Child string
+	// Allowed is the list of allowed categories and types for NotAllowed, or the list of allowed parents for WrongParent.
+	Allowed []string
+	// Max is the maximum number of occurrences for TooMany.
+	Max int
+}
+
+// Error implements the error interface.
+func (e *NestingError) Error() string {
+	switch e.Kind {
+	case WrongParent:
+		return fmt.Sprintf("%s must be directly inside one of %s, not %s", nestingName(e.Child), nestingNames(e.Allowed), nestingName(e.Parent))
+	case TooMany:
+		return fmt.Sprintf("%s arg %d may contain %s at most %d times", nestingName(e.Parent), e.Arg, nestingName(e.Child), e.Max)
+	default:
+		return fmt.Sprintf("%s is not allowed in %s arg %d, only %s", nestingName(e.Child), nestingName(e.Parent), e.Arg, nestingNames(e.Allowed))
+	}
+}
+
+// nestingName formats a syntax type, category or special name for use in a NestingError.
+func nestingName(t string) string {
+	switch t {
+	case TextContent:
+		return "text"
+	case RootParent:
+		return "the root"
+	}
+	return "@" + t
+}
+
+// nestingNames formats a list of allowed names for use in a NestingError.
+// As these may be categories as well as syntax types, they are not prefixed with '@'.
+func nestingNames(ts []string) string {
+	names := make([]string, len(ts))
+	for i, t := range ts {
+		names[i] = strings.TrimPrefix(nestingName(t), "@")
+	}
+	return strings.Join(names, ", ")
+}
+
+// contentModelOf returns the content model of the node, or nil if it does not have one.
+func contentModelOf(n SemNode) *ContentModel {
+	if cm, ok := n.(ContentModelSemNode); ok {
+		m := cm.ContentModel()
+		return &m
+	}
+	return nil
+}
+
+// contentNames returns the names that a child can be matched by in a content model: its syntax type and its categories.
+// Aliases are resolved, so the content model only needs to refer to the registered name.
+func (p *semParser) contentNames(e SynElement) []string {
+	obj, ok := e.(*ObjectSynNode)
+	if !ok {
+		return []string{TextContent}
+	}
+	names := []string{p.reg.canonical(obj.Type)}
+	if sem, ok := p.reg.Lookup(obj.Type); ok {
+		if m := contentModelOf(sem); m != nil {
+			names = append(names, m.Categories...)
+		}
+	}
+	return names
+}
+
+// checkParent checks that a child is allowed in the given argument of its parent, and that the parent is allowed by the child.
+// Unknown objects that are kept have no content model, so are checked like any other node without one.
+func (p *semParser) checkParent(e SynElement, parentPath ObjectPath, argIndex int) error {
+	if p.unknownMode == UnknownUnwrap {
+		// Unwrapped objects are replaced by their contents, so the contents end up in the nearest known ancestor
+		for len(parentPath) > 0 && !p.reg.has(parentPath[len(parentPath)-1].Type) {
+			argIndex = parentPath[len(parentPath)-1].Arg
+			parentPath = parentPath[:len(parentPath)-1]
+		}
+	}
+	parent := RootParent
+	if len(parentPath) > 0 {
+		parent = p.reg.canonical(parentPath[len(parentPath)-1].Type)
+	}
+	names := p.contentNames(e)
+	untyped := false
+	if obj, ok := e.(*ObjectSynNode); ok {
+		untyped = true
+		if sem, ok := p.reg.Lookup(obj.Type); ok {
+			m := contentModelOf(sem)
+			if m != nil && len(m.Parents) > 0 && !slices.Contains(m.Parents, parent) {
+				return &NestingError{Kind: WrongParent, Parent: parent, Arg: argIndex, Child: names[0], Allowed: m.Parents}
+			}
+			untyped = m == nil
+		}
+	}
+	if parent == RootParent {
+		return nil
+	}
+	parentSem, ok := p.reg.Lookup(parent)
+	if !ok {
+		return nil
+	}
+	m := contentModelOf(parentSem)
+	if m == nil {
+		return nil
+	}
+	rule := m.forArg(argIndex)
+	if rule == nil || rule.Allow == nil {
+		return nil
+	}
+	for _, n := range names {
+		if slices.Contains(rule.Allow, n) {
+			return nil
+		}
+	}
+	if untyped && allowsObjects(rule.Allow) {
+		// Nodes without a content model have no categories to match, so they may be used anywhere that objects may be
+		return nil
+	}
+	return &NestingError{Kind: NotAllowed, Parent: parent, Arg: argIndex, Child: names[0], Allowed: rule.Allow}
+}
+
+// allowsObjects returns whether an allow list contains anything other than TextContent.
+func allowsObjects(allow []string) bool {
+	for _, a := range allow {
+		if a != TextContent {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOccurrences checks that no child of the object is used more times than allowed by its content model.
+// It returns the offending child along with the error.
+func (p *semParser) checkOccurrences(sem SemNode, node *ObjectSynNode) (SynElement, error) {
+	m := contentModelOf(sem)
+	if m == nil {
+		return nil, nil
+	}
+	for i, arg := range node.Args {
+		rule := m.forArg(i)
+		if rule == nil || len(rule.MaxOccurs) == 0 {
+			continue
+		}
+		counts := make(map[string]int)
+		for _, e := range p.contentElements(arg.Elements) {
+			for _, n := range p.contentNames(e) {
+				counts[n]++
+				if max, ok := rule.MaxOccurs[n]; ok && counts[n] > max {
+					return e, &NestingError{Kind: TooMany, Parent: node.Type, Arg: i, Child: n, Max: max}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// contentElements returns the elements of an arg as they will be after parsing, following the UnknownMode:
+// dropped unknown objects are removed, and unwrapped ones are replaced by the contents of their args.
+func (p *semParser) contentElements(elements []SynElement) []SynElement {
+	if p.unknownMode != UnknownDrop && p.unknownMode != UnknownUnwrap {
+		return elements
+	}
+	res := make([]SynElement, 0, len(elements))
+	for _, e := range elements {
+		obj, ok := e.(*ObjectSynNode)
+		if !ok || p.reg.has(obj.Type) {
+			res = append(res, e)
+			continue
+		}
+		if p.unknownMode == UnknownUnwrap {
+			for _, a := range obj.Args {
+				res = append(res, p.contentElements(a.Elements)...)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,139 @@
+package obtext
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testArticle struct{ SingleArgSemNode }
+
+func (*testArticle) SyntaxType() string { return "article" }
+
+func (*testArticle) ContentModel() ContentModel {
+	return ContentModel{
+		Parents: []string{RootParent},
+		Args:    []ArgContentModel{{Allow: []string{"block"}, MaxOccurs: map[string]int{"title": 1}}},
+	}
+}
+
+type testTitle struct{ SingleArgSemNode }
+
+func (*testTitle) SyntaxType() string { return "title" }
+
+func (*testTitle) ContentModel() ContentModel {
+	return ContentModel{
+		Categories: []string{"block"},
+		Parents:    []string{"article"},
+		Args:       []ArgContentModel{{Allow: []string{TextContent}}},
+	}
+}
+
+type testNote struct{ SingleArgSemNode }
+
+func (*testNote) SyntaxType() string { return "note" }
+
+func (*testNote) ContentModel() ContentModel {
+	return ContentModel{
+		Categories: []string{"block"},
+		Args:       []ArgContentModel{{Allow: []string{TextContent, "inline"}}},
+	}
+}
+
+type testEm struct{ SingleArgSemNode }
+
+func (*testEm) SyntaxType() string { return "em" }
+
+func (*testEm) ContentModel() ContentModel {
+	return ContentModel{Categories: []string{"inline"}}
+}
+
+// nestingSemantics returns semantics with content models, along with the untyped @bold.
+func nestingSemantics() []SemNode {
+	return []SemNode{&testArticle{}, &testTitle{}, &testNote{}, &testEm{}, &testBold{}}
+}
+
+func TestContentModelAllowed(t *testing.T) {
+	srcs := []string{
+		"@article{@title{a} @note{b}}",
+		"@article{@note{some @em{emphasised} text}}",
+		// Nodes without a content model may be used anywhere that objects may be
+		"@article{@bold{x}}",
+		"@article{@note{@bold{x}}}",
+	}
+	for _, src := range srcs {
+		if _, err := ParseSem(mustParseSyn(t, src), nestingSemantics()); err != nil {
+			t.Errorf("%s: unexpected error: %v", src, err)
+		}
+	}
+}
+
+func TestContentModelErrors(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected NestingError
+	}{
+		{"@article{hello}", NestingError{Kind: NotAllowed, Parent: "article", Arg: 0, Child: TextContent, Allowed: []string{"block"}}},
+		{"@article{@em{x}}", NestingError{Kind: NotAllowed, Parent: "article", Arg: 0, Child: "em", Allowed: []string{"block"}}},
+		{"@article{@title{@bold{x}}}", NestingError{Kind: NotAllowed, Parent: "title", Arg: 0, Child: "bold", Allowed: []string{TextContent}}},
+		{"@article{@note{@title{x}}}", NestingError{Kind: WrongParent, Parent: "note", Arg: 0, Child: "title", Allowed: []string{"article"}}},
+		{"@title{x}", NestingError{Kind: WrongParent, Parent: RootParent, Arg: -1, Child: "title", Allowed: []string{"article"}}},
+		{"@note{@article{@note{x}}}", NestingError{Kind: WrongParent, Parent: "note", Arg: 0, Child: "article", Allowed: []string{RootParent}}},
+		{"@article{@title{a}@title{b}}", NestingError{Kind: TooMany, Parent: "article", Arg: 0, Child: "title", Max: 1}},
+	}
+	for _, c := range cases {
+		_, err := ParseSem(mustParseSyn(t, c.src), nestingSemantics())
+		var nestErr *NestingError
+		if !errors.As(err, &nestErr) {
+			t.Errorf("%s: expected a NestingError, got %v", c.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(*nestErr, c.expected) {
+			t.Errorf("%s: got %+v, want %+v", c.src, *nestErr, c.expected)
+		}
+	}
+}
+
+func TestContentModelErrorPosition(t *testing.T) {
+	_, err := ParseSem(mustParseSyn(t, "@article{@title{a}\n@title{b}}"), nestingSemantics())
+	var semErr *SemError
+	if !errors.As(err, &semErr) {
+		t.Fatalf("expected a SemError, got %v", err)
+	}
+	// The error is at the child that was used too many times
+	if semErr.Pos.Line != 2 || semErr.Pos.Column != 1 {
+		t.Errorf("got position %s, want 2:1", semErr.Pos)
+	}
+}
+
+func TestContentModelWithAlias(t *testing.T) {
+	reg, err := NewRegistry(nestingSemantics()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Alias("t", "title"); err != nil {
+		t.Fatal(err)
+	}
+	// The alias is resolved, so it counts towards the occurrences of @title
+	_, err = reg.Parse(mustParseSyn(t, "@article{@t{a}@title{b}}"))
+	var nestErr *NestingError
+	if !errors.As(err, &nestErr) || nestErr.Kind != TooMany || nestErr.Child != "title" {
+		t.Errorf("expected too many titles, got %v", err)
+	}
+}
+
+func TestNestingErrorMessages(t *testing.T) {
+	cases := []struct {
+		err      *NestingError
+		expected string
+	}{
+		{&NestingError{Kind: NotAllowed, Parent: "article", Arg: 0, Child: TextContent, Allowed: []string{"block", "em"}}, "text is not allowed in @article arg 0, only block, em"},
+		{&NestingError{Kind: WrongParent, Parent: RootParent, Arg: -1, Child: "title", Allowed: []string{"article"}}, "@title must be directly inside one of article, not the root"},
+		{&NestingError{Kind: TooMany, Parent: "article", Arg: 1, Child: "title", Max: 1}, "@article arg 1 may contain @title at most 1 times"},
+	}
+	for _, c := range cases {
+		if got := c.err.Error(); got != c.expected {
+			t.Errorf("got %q, want %q", got, c.expected)
+		}
+	}
+}
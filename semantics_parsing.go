@@ -1,49 +1,264 @@
 package obtext
 
 import (
-	"fmt"
-	"reflect"
+	"errors"
 )
 
+// SemOption is an option that changes the behaviour of ParseSem.
+type SemOption func(*semParser)
+
+// WithAllErrors makes ParseSem continue after an object fails to parse, so that every semantic error in the document is found.
+// The returned error is then a join of all SemErrors (see errors.Join), which can be split up again with SemErrors.
+func WithAllErrors() SemOption {
+	return func(p *semParser) {
+		p.collectAll = true
+	}
+}
+
+// UnknownMode decides what ParseSem does with objects whose type is not defined in the semantics.
+type UnknownMode int
+
+const (
+	// UnknownError makes an unknown object an UnknownObjectError. This is the default.
+	UnknownError UnknownMode = iota
+	// UnknownDrop removes unknown objects, along with their contents, from the semantic tree.
+	UnknownDrop
+	// UnknownKeep replaces unknown objects with an UnknownSemNode, which keeps the type and parsed args.
+	UnknownKeep
+	// UnknownUnwrap replaces unknown objects with the contents of all of their args.
+	UnknownUnwrap
+)
+
+// WithUnknownObjects sets what ParseSem does with objects whose type is not defined, which is useful for previews and migrations.
+// The contents of unknown objects are still parsed, so any errors in them are still reported.
+// Content models are still enforced: kept objects may be used wherever objects are allowed,
+// and the contents of unwrapped objects must be allowed where they end up.
+// If the root object is dropped or unwrapped, the result is a ContentBlockSemNode of whatever remains.
+func WithUnknownObjects(mode UnknownMode) SemOption {
+	return func(p *semParser) {
+		p.unknownMode = mode
+	}
+}
+
 // ParseSem parses the given syntax tree and returns the semantics tree, or an error if the syntax tree is invalid.
+// The syntax tree is usually an *ObjectSynNode, but may be an *ArgSynNode to parse a list of elements into a ContentBlockSemNode.
 // It parses based on the given semantics, which is a list of all possible semantic nodes.
 // Each node contains information about what @<syntax-type> it should match, and how to parse its arguments.
-func ParseSem(node any, semantics []SemNode) (SemNode, error) {
-	semanticLookup := make(map[string]SemNode)
+// Each parsed node is constructed from the matching node in semantics, keeping any fields that were set on it (see CloneableSemNode).
+// Any returned error is a *SemError, which contains the position and object path of the failing object,
+// unless one of the semantics cannot be constructed.
+// If more than one node has the same SyntaxType, the last one is used. To detect this, or to use aliases, use a Registry instead.
+// A registry is built from the semantics on every call, so to parse many documents with the same semantics, build a Registry once and use Registry.Parse.
+func ParseSem(node any, semantics []SemNode, opts ...SemOption) (SemNode, error) {
+	reg, _ := NewRegistry()
 	for _, o := range semantics {
-		semanticLookup[o.SyntaxType()] = o
+		if err := reg.Override(o); err != nil {
+			return nil, err
+		}
+	}
+	return parseSemWithRegistry(node, reg, opts)
+}
+
+func parseSemWithRegistry(node any, reg *Registry, opts []SemOption) (SemNode, error) {
+	p := &semParser{
+		reg:     reg,
+		store:   make(map[any]any),
+		origins: make(map[SemNode]nodeOrigin),
 	}
-	return parseSem(node, semanticLookup)
+	for _, opt := range opts {
+		opt(p)
+	}
+	res, err := p.parse(node, nil, nil, -1, -1)
+	if len(p.errs) > 0 {
+		return nil, errors.Join(p.errs...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var root SemNode
+	// A dropped or unwrapped root is always a content block, even if exactly one element is left
+	obj, isObj := node.(*ObjectSynNode)
+	if len(res) != 1 || (isObj && !reg.has(obj.Type) && p.unknownMode != UnknownKeep) {
+		root = &ContentBlockSemNode{Elements: res}
+	} else {
+		root = res[0]
+	}
+	root, err = p.runPasses(root)
+	if err != nil {
+		return nil, err
+	}
+	if p.sourceMap != nil {
+		p.sourceMap.origins = p.origins
+	}
+	return root, nil
+}
+
+// semParser holds the state of a single call to ParseSem.
+type semParser struct {
+	reg         *Registry
+	collectAll  bool
+	unknownMode UnknownMode
+	metadata    map[string]any
+	store       map[any]any
+	passes      []Pass
+	warnings    *[]*SemError
+	// origins is where each parsed node came from, for use by passes and the source map.
+	origins   map[SemNode]nodeOrigin
+	sourceMap *SourceMap
+	errs      []error
 }
 
-func parseSem(node any, semanticLookup map[string]SemNode) (SemNode, error) {
+// errSkipped is returned up the tree when collecting all errors, to signal that a node failed but its error has already been recorded.
+var errSkipped = errors.New("skipped due to previous errors")
+
+// fail wraps the error with position information and either records it or returns it.
+// Typed errors that do not know which object they came from are given its type.
+func (p *semParser) fail(pos Position, objType string, path ObjectPath, err error) error {
+	var typed typedSemError
+	if errors.As(err, &typed) {
+		typed.setType(objType)
+	}
+	err = &SemError{Pos: pos, Path: path, Err: err, Hint: hintFor(objType, err)}
+	if p.collectAll {
+		p.errs = append(p.errs, err)
+		return errSkipped
+	}
+	return err
+}
+
+// elementPos returns the position of a syntax element.
+func elementPos(e SynElement) Position {
+	switch e := e.(type) {
+	case *ObjectSynNode:
+		return e.Pos
+	case *TextSynNode:
+		return e.Pos
+	}
+	return Position{}
+}
+
+// parse parses a syntax element into the semantic nodes that replace it, which is usually exactly one node,
+// but may be none or many depending on the UnknownMode.
+func (p *semParser) parse(node any, parent *ObjectSynNode, parentPath ObjectPath, argIndex, index int) ([]SemNode, error) {
 	switch node := node.(type) {
 	case *ObjectSynNode:
-		if sem, ok := semanticLookup[node.Type]; !ok {
-			return nil, fmt.Errorf("object '%s' was not defined", node.Type)
-		} else {
-			// First parse all children of all args
-			parsedArgs := make([]*ContentBlockSemNode, len(node.Args))
-			for i, arg := range node.Args {
-				parsedArgs[i] = &ContentBlockSemNode{Elements: make([]SemNode, len(arg.Elements))}
-				for j, e := range arg.Elements {
-					parsed, err := parseSem(e, semanticLookup)
-					if err != nil {
+		path := parentPath.with(PathElement{Type: node.Type, Arg: argIndex, Index: index})
+		sem, ok := p.reg.Lookup(node.Type)
+		failed := false
+		if !ok && p.unknownMode == UnknownError {
+			err := p.fail(node.Pos, node.Type, path, &UnknownObjectError{
+				Type:        node.Type,
+				Suggestions: suggestTypes(node.Type, p.reg.Names()),
+			})
+			if !p.collectAll {
+				return nil, err
+			}
+			// Keep going so that errors in the children of the unknown object are also found
+			failed = true
+		} else if ok || p.unknownMode == UnknownKeep {
+			if err := p.checkParent(node, parentPath, argIndex); err != nil {
+				err = p.fail(node.Pos, node.Type, path, err)
+				if !p.collectAll {
+					return nil, err
+				}
+				failed = true
+			}
+		}
+		// First parse all children of all args
+		parsedArgs := make([]*ContentBlockSemNode, len(node.Args))
+		for i, arg := range node.Args {
+			parsedArgs[i] = &ContentBlockSemNode{Elements: make([]SemNode, 0, len(arg.Elements))}
+			for j, e := range arg.Elements {
+				parsed, err := p.parse(e, node, path, i, j)
+				if err != nil {
+					if !p.collectAll {
 						return nil, err
 					}
-					parsedArgs[i].Elements[j] = parsed
+					failed = true
+					continue
 				}
+				parsedArgs[i].Elements = append(parsedArgs[i].Elements, parsed...)
 			}
-			// Now using our new semantic children args, parse the object
-			newNode := reflect.New(reflect.TypeOf(sem).Elem()).Interface().(SemNode)
-			if err := newNode.ParseArgs(parsedArgs); err != nil {
-				return nil, err
+		}
+		if failed {
+			return nil, errSkipped
+		}
+		if !ok {
+			return p.fallback(node, path, parsedArgs), nil
+		}
+		if e, err := p.checkOccurrences(sem, node); err != nil {
+			return nil, p.fail(elementPos(e), node.Type, path, err)
+		}
+		// Cast any typed args, so that the node can use the cast values
+		if err := castArgs(sem, parsedArgs); err != nil {
+			return nil, p.fail(node.Pos, node.Type, path, err)
+		}
+		// Now using our new semantic children args, parse the object
+		newNode, _ := p.reg.New(node.Type)
+		var err error
+		if cn, ok := newNode.(ContextSemNode); ok {
+			err = cn.ParseArgsWithContext(&ParseContext{
+				Node:     node,
+				Parent:   parent,
+				Path:     path,
+				Metadata: p.metadata,
+				store:    p.store,
+			}, parsedArgs)
+		} else {
+			err = newNode.ParseArgs(parsedArgs)
+		}
+		if err != nil {
+			return nil, p.fail(node.Pos, node.Type, path, err)
+		}
+		for i, arg := range node.Args {
+			arg.CastValue = parsedArgs[i].CastValue
+		}
+		p.origins[newNode] = nodeOrigin{pos: node.Pos, path: path}
+		return []SemNode{newNode}, nil
+	case *ArgSynNode:
+		// An arg is only parsed as the root, as the syntax of a ContentBlockSemNode, whose elements are all at the root
+		block := &ContentBlockSemNode{Elements: make([]SemNode, 0, len(node.Elements))}
+		failed := false
+		for i, e := range node.Elements {
+			parsed, err := p.parse(e, nil, nil, -1, i)
+			if err != nil {
+				if !p.collectAll {
+					return nil, err
+				}
+				failed = true
+				continue
 			}
-			return newNode, nil
+			block.Elements = append(block.Elements, parsed...)
 		}
+		if failed {
+			return nil, errSkipped
+		}
+		return []SemNode{block}, nil
 	case *TextSynNode:
-		return &TextSemNode{Text: node.Value}, nil
+		if err := p.checkParent(node, parentPath, argIndex); err != nil {
+			return nil, p.fail(node.Pos, "", parentPath, err)
+		}
+		text := &TextSemNode{Text: node.Value}
+		p.origins[text] = nodeOrigin{pos: node.Pos, path: parentPath}
+		return []SemNode{text}, nil
 
 	}
 	panic("unknown type")
 }
+
+// fallback returns the nodes that replace an unknown object, according to the UnknownMode.
+func (p *semParser) fallback(node *ObjectSynNode, path ObjectPath, parsedArgs []*ContentBlockSemNode) []SemNode {
+	switch p.unknownMode {
+	case UnknownKeep:
+		unknown := &UnknownSemNode{Type: node.Type, Args: parsedArgs, Pos: node.Pos}
+		p.origins[unknown] = nodeOrigin{pos: node.Pos, path: path}
+		return []SemNode{unknown}
+	case UnknownUnwrap:
+		res := make([]SemNode, 0)
+		for _, a := range parsedArgs {
+			res = append(res, a.Elements...)
+		}
+		return res
+	}
+	return nil
+}
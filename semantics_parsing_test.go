@@ -0,0 +1,118 @@
+package obtext
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnknownObjectModes(t *testing.T) {
+	src := "@doc{a @foo{b @bold{c}}{d} e}"
+	cases := []struct {
+		mode     UnknownMode
+		expected string
+	}{
+		{UnknownDrop, "a  e"},
+		{UnknownKeep, "a b cd e"},
+		{UnknownUnwrap, "a b cd e"},
+	}
+	for _, c := range cases {
+		sem := mustParseTestSem(t, src, WithUnknownObjects(c.mode))
+		if got := textOf(sem); got != c.expected {
+			t.Errorf("mode %d: got text %q, want %q", c.mode, got, c.expected)
+		}
+	}
+	if _, err := parseTestSem(t, src); err == nil {
+		t.Errorf("expected an error for an unknown object by default")
+	}
+}
+
+func TestUnknownKeep(t *testing.T) {
+	sem := mustParseTestSem(t, "@doc{@foo{a}{@bold{b}}}", WithUnknownObjects(UnknownKeep))
+	unknown, ok := body(sem)[0].(*UnknownSemNode)
+	if !ok {
+		t.Fatalf("got %T, want an UnknownSemNode", body(sem)[0])
+	}
+	if unknown.Type != "foo" || len(unknown.Args) != 2 || unknown.Pos.Column != 6 {
+		t.Errorf("got %+v", unknown)
+	}
+	if !isType[*testBold](unknown.Args[1].Elements[0]) {
+		t.Errorf("the args of the unknown object were not parsed")
+	}
+}
+
+func TestUnknownRoot(t *testing.T) {
+	sem := mustParseTestSem(t, "@foo{a @bold{b}}", WithUnknownObjects(UnknownUnwrap))
+	block, ok := sem.(*ContentBlockSemNode)
+	if !ok || len(block.Elements) != 2 {
+		t.Fatalf("got %#v, want a content block of the unwrapped contents", sem)
+	}
+	sem = mustParseTestSem(t, "@foo{a}", WithUnknownObjects(UnknownDrop))
+	if block, ok := sem.(*ContentBlockSemNode); !ok || len(block.Elements) != 0 {
+		t.Errorf("got %#v, want an empty content block", sem)
+	}
+	// A single element is still wrapped, so that the root is never mistaken for a known root object
+	sem = mustParseTestSem(t, "@foo{@bold{b}}", WithUnknownObjects(UnknownUnwrap))
+	if block, ok := sem.(*ContentBlockSemNode); !ok || len(block.Elements) != 1 {
+		t.Errorf("got %#v, want a content block of the single unwrapped element", sem)
+	} else if _, ok := block.Elements[0].(*testBold); !ok {
+		t.Errorf("got %#v, want the unwrapped bold node", block.Elements[0])
+	}
+	sem = mustParseTestSem(t, "@foo{a}", WithUnknownObjects(UnknownUnwrap))
+	if block, ok := sem.(*ContentBlockSemNode); !ok || len(block.Elements) != 1 {
+		t.Errorf("got %#v, want a content block of the single unwrapped text", sem)
+	}
+	// A kept root is not wrapped
+	if _, ok := mustParseTestSem(t, "@foo{a}", WithUnknownObjects(UnknownKeep)).(*UnknownSemNode); !ok {
+		t.Errorf("got %#v, want the kept root", sem)
+	}
+}
+
+func TestUnknownContentsStillReportErrors(t *testing.T) {
+	_, err := parseTestSem(t, "@doc{@foo{@bar{x}}}", WithUnknownObjects(UnknownKeep), WithAllErrors())
+	if err != nil {
+		t.Errorf("unexpected error for nested unknown objects: %v", err)
+	}
+	_, err = parseTestSem(t, "@doc{@foo{@link{only one}}}", WithUnknownObjects(UnknownUnwrap))
+	var countErr *ArgCountError
+	if !errors.As(err, &countErr) {
+		t.Errorf("expected an ArgCountError inside the unknown object, got %v", err)
+	}
+}
+
+func TestUnknownObjectsFollowContentModels(t *testing.T) {
+	cases := []struct {
+		src  string
+		mode UnknownMode
+		// kind is the expected kind of NestingError, or -1 if there should be no error.
+		kind   NestingErrorKind
+		parent string
+	}{
+		{"@article{@foo{@title{a}}}", UnknownUnwrap, -1, ""},
+		{"@article{@foo{@bar{@note{a}}}}", UnknownUnwrap, -1, ""},
+		{"@article{@foo{text}}", UnknownUnwrap, NotAllowed, "article"},
+		{"@article{@note{@foo{@title{x}}}}", UnknownUnwrap, WrongParent, "note"},
+		{"@article{@title{@foo{@note{x}}}}", UnknownUnwrap, NotAllowed, "title"},
+		{"@article{@title{a} @foo{@title{b}}}", UnknownUnwrap, TooMany, "article"},
+		{"@article{@title{a} @foo{@title{b}}}", UnknownKeep, WrongParent, "foo"},
+		{"@article{@foo{x}}", UnknownKeep, -1, ""},
+		{"@article{@note{@foo{x}}}", UnknownKeep, -1, ""},
+		{"@article{@title{@foo{x}}}", UnknownKeep, NotAllowed, "title"},
+	}
+	for _, c := range cases {
+		_, err := ParseSem(mustParseSyn(t, c.src), nestingSemantics(), WithUnknownObjects(c.mode))
+		if c.kind < 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", c.src, err)
+			}
+			continue
+		}
+		var nestErr *NestingError
+		if !errors.As(err, &nestErr) {
+			t.Errorf("%s: expected a NestingError, got %v", c.src, err)
+			continue
+		}
+		if nestErr.Kind != c.kind || nestErr.Parent != c.parent {
+			t.Errorf("%s: got %v, want kind %d in %s", c.src, nestErr, c.kind, c.parent)
+		}
+	}
+}
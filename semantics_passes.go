@@ -0,0 +1,116 @@
+package obtext
+
+import "errors"
+
+// Pass is a step that is run on the whole semantic tree after it has been parsed, such as numbering sections or resolving references.
+// A pass may modify the tree, including replacing the root, and report problems with PassContext.Report or PassContext.Warn.
+// If a pass returns an error, parsing stops and that error is returned.
+type Pass func(ctx *PassContext) error
+
+// ResolvableSemNode is an optional interface for SemNodes that need to do something once the whole tree has been parsed.
+// After all passes have run, Resolve is called on every node in the tree that implements it, in document order.
+type ResolvableSemNode interface {
+	SemNode
+	// Resolve is called once the whole tree has been parsed and all passes have run.
+	Resolve(ctx *PassContext) error
+}
+
+// WithPasses adds passes that ParseSem runs, in order, after the tree has been parsed successfully.
+func WithPasses(passes ...Pass) SemOption {
+	return func(p *semParser) {
+		p.passes = append(p.passes, passes...)
+	}
+}
+
+// WithWarnings makes ParseSem append any warnings from PassContext.Warn to warnings, which are otherwise ignored.
+// Warnings do not make ParseSem fail, and are kept even if it fails for another reason.
+func WithWarnings(warnings *[]*SemError) SemOption {
+	return func(p *semParser) {
+		p.warnings = warnings
+	}
+}
+
+// PassContext gives a Pass or ResolvableSemNode access to the parsed tree and the state of the parse.
+type PassContext struct {
+	// Root is the root of the semantic tree, which a pass can replace.
+	Root SemNode
+	// Metadata is the document metadata given with WithMetadata, which may be nil.
+	Metadata map[string]any
+	p        *semParser
+	reported []error
+}
+
+// Value returns the value stored for the key in this parse, including values stored by ContextSemNodes during parsing.
+func (c *PassContext) Value(key any) any {
+	return c.p.store[key]
+}
+
+// SetValue stores a value for the key, which can be read by later passes and nodes.
+func (c *PassContext) SetValue(key, value any) {
+	c.p.store[key] = value
+}
+
+// Pos returns the position in the source of the object or text that the node was parsed from.
+// It is not valid for nodes that were created by a pass, or for ContentBlockSemNodes.
+func (c *PassContext) Pos(n SemNode) Position {
+	return c.p.origins[n].pos
+}
+
+// Path returns the path of objects from the root to the object that the node was parsed from.
+// It is nil for nodes that were created by a pass, or for ContentBlockSemNodes.
+func (c *PassContext) Path(n SemNode) ObjectPath {
+	return c.p.origins[n].path
+}
+
+// Report records a problem with the given node, which makes ParseSem fail once all passes have run.
+// The error is wrapped in a SemError with the position and path of the node.
+func (c *PassContext) Report(n SemNode, err error) {
+	c.reported = append(c.reported, c.semError(n, err))
+}
+
+// Warn records a problem with the given node that should not make ParseSem fail, such as the use of a deprecated object.
+// Like Report, the error is wrapped in a SemError. It is only kept if ParseSem was given WithWarnings.
+func (c *PassContext) Warn(n SemNode, err error) {
+	if c.p.warnings != nil {
+		*c.p.warnings = append(*c.p.warnings, c.semError(n, err))
+	}
+}
+
+// semError wraps the error with the position and path of the node.
+func (c *PassContext) semError(n SemNode, err error) *SemError {
+	origin := c.p.origins[n]
+	return &SemError{Pos: origin.pos, Path: origin.path, Err: err}
+}
+
+// nodeOrigin is where a semantic node was parsed from.
+type nodeOrigin struct {
+	pos  Position
+	path ObjectPath
+}
+
+// runPasses runs all passes then resolves all nodes, returning the new root.
+func (p *semParser) runPasses(root SemNode) (SemNode, error) {
+	ctx := &PassContext{Root: root, Metadata: p.metadata, p: p}
+	for _, pass := range p.passes {
+		if err := pass(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var resolveErr error
+	Walk(ctx.Root, func(n, _ SemNode) bool {
+		if resolveErr != nil {
+			return false
+		}
+		if r, ok := n.(ResolvableSemNode); ok {
+			resolveErr = r.Resolve(ctx)
+		}
+		return resolveErr == nil
+	})
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	if len(ctx.reported) > 0 {
+		return nil, errors.Join(ctx.reported...)
+	}
+	return ctx.Root, nil
+}
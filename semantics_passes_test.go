@@ -0,0 +1,130 @@
+package obtext
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testRef refers to a figure by its text, and is resolved once the whole document has been parsed.
+type testRef struct {
+	SingleArgSemNode
+	Resolved bool
+}
+
+func (*testRef) SyntaxType() string { return "ref" }
+
+func (r *testRef) Resolve(ctx *PassContext) error {
+	figures, _ := ctx.Value(figuresKey{}).(map[string]bool)
+	if !figures[textOf(r)] {
+		ctx.Report(r, errors.New("no such figure"))
+		return nil
+	}
+	r.Resolved = true
+	return nil
+}
+
+// figuresKey is the key that collectFigures stores the figures by.
+type figuresKey struct{}
+
+// collectFigures is a pass that stores the text of every @bold, standing in for figures.
+func collectFigures(ctx *PassContext) error {
+	figures := make(map[string]bool)
+	Walk(ctx.Root, func(n, _ SemNode) bool {
+		if b, ok := n.(*testBold); ok {
+			figures[textOf(b)] = true
+		}
+		return true
+	})
+	ctx.SetValue(figuresKey{}, figures)
+	return nil
+}
+
+func passSemantics() []SemNode {
+	return append(testSemantics(), &testRef{})
+}
+
+func TestPassesAndResolve(t *testing.T) {
+	// The reference comes before the figure, so can only be resolved after parsing
+	sem, err := ParseSem(mustParseSyn(t, "@doc{@ref{a} @bold{a}}"), passSemantics(), WithPasses(collectFigures))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !body(sem)[0].(*testRef).Resolved {
+		t.Errorf("the reference was not resolved")
+	}
+}
+
+func TestPassesRunInOrder(t *testing.T) {
+	var order []string
+	pass := func(name string) Pass {
+		return func(ctx *PassContext) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	replace := func(ctx *PassContext) error {
+		ctx.Root = &ContentBlockSemNode{Elements: []SemNode{ctx.Root}}
+		return nil
+	}
+	sem, err := parseTestSem(t, "@doc{a}", WithPasses(pass("a"), pass("b")), WithPasses(replace, pass("c")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []string{"a", "b", "c"}) {
+		t.Errorf("got order %v", order)
+	}
+	if !isType[*ContentBlockSemNode](sem) {
+		t.Errorf("the root was not replaced, got %T", sem)
+	}
+}
+
+func TestPassError(t *testing.T) {
+	failed := errors.New("failed")
+	ran := false
+	_, err := parseTestSem(t, "@doc{a}", WithPasses(
+		func(*PassContext) error { return failed },
+		func(*PassContext) error { ran = true; return nil },
+	))
+	if !errors.Is(err, failed) {
+		t.Errorf("got %v, want the error from the pass", err)
+	}
+	if ran {
+		t.Errorf("a pass ran after an error")
+	}
+}
+
+func TestPassReport(t *testing.T) {
+	_, err := ParseSem(mustParseSyn(t, "@doc{@ref{a}\n@ref{b} @bold{a}}"), passSemantics(), WithPasses(collectFigures))
+	errs := SemErrors(err)
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), err)
+	}
+	if errs[0].Pos.Line != 2 || errs[0].Path.String() != "doc > ref[1]" {
+		t.Errorf("got error %v", errs[0])
+	}
+}
+
+func TestPassWarn(t *testing.T) {
+	deprecated := func(ctx *PassContext) error {
+		Walk(ctx.Root, func(n, _ SemNode) bool {
+			if _, ok := n.(*testCode); ok {
+				ctx.Warn(n, errors.New("@code is deprecated"))
+			}
+			return true
+		})
+		return nil
+	}
+	var warnings []*SemError
+	sem, err := parseTestSem(t, "@doc{a @code{go}{x}}", WithPasses(deprecated), WithWarnings(&warnings))
+	if err != nil || sem == nil {
+		t.Fatalf("a warning made parsing fail: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Error() != "1:8: doc > code[1]: @code is deprecated" {
+		t.Errorf("got warnings %v", warnings)
+	}
+	// Without WithWarnings, warnings are ignored
+	if _, err := parseTestSem(t, "@doc{a @code{go}{x}}", WithPasses(deprecated)); err != nil {
+		t.Errorf("a warning made parsing fail: %v", err)
+	}
+}
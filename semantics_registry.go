@@ -0,0 +1,292 @@
+package obtext
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Registry is a set of semantic nodes that can be used to parse a syntax tree, keyed by the name that they are used with in the syntax.
+// Unlike passing a list of nodes to ParseSem, a Registry rejects nodes that use the same name unless they are explicitly overriding,
+// and supports aliases and namespaced packs of nodes.
+// The zero value is not usable, use NewRegistry instead.
+type Registry struct {
+	nodes   map[string]registryEntry
+	aliases map[string]string
+}
+
+// registryEntry is a single node in a Registry.
+type registryEntry struct {
+	// proto is the registered node, which is used to find out about the node, but is never parsed into.
+	proto SemNode
+	// construct creates a new node to parse into.
+	construct func() SemNode
+}
+
+// CloneableSemNode is an optional interface for SemNodes that can create a new node to parse into.
+// This allows a registered node to carry configuration, such as a base URL, which is passed on to each parsed node.
+type CloneableSemNode interface {
+	SemNode
+	// Clone returns a new node with the same configuration as this one.
+	// It must not share any mutable state with this node, as ParseArgs will be called on it.
+	Clone() SemNode
+}
+
+// newRegistryEntry creates an entry for the node, deciding how new nodes will be constructed:
+//   - if the node is a CloneableSemNode, Clone is used
+//   - if the node is a pointer to a struct, a shallow copy of the struct is used, so any fields set on the registered node are kept
+//
+// Any other node is invalid, and should instead be registered with RegisterFactory.
+func newRegistryEntry(n SemNode) (registryEntry, error) {
+	if err := checkNode(n); err != nil {
+		return registryEntry{}, err
+	}
+	if c, ok := n.(CloneableSemNode); ok {
+		return registryEntry{proto: n, construct: c.Clone}, nil
+	}
+	t := reflect.TypeOf(n)
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return registryEntry{}, fmt.Errorf("cannot construct nodes from %T, as it is not a pointer to a struct, so it must implement CloneableSemNode or be registered with a factory", n)
+	}
+	t, v := t.Elem(), reflect.ValueOf(n).Elem()
+	return registryEntry{
+		proto: n,
+		construct: func() SemNode {
+			nv := reflect.New(t)
+			nv.Elem().Set(v)
+			return nv.Interface().(SemNode)
+		},
+	}, nil
+}
+
+// checkNode returns an error if the node can never parse its args, such as an Args2 whose ArgTypes are in an order that cannot be parsed.
+func checkNode(n SemNode) error {
+	if g, ok := n.(genericSemNode); ok {
+		if err := checkGenericSlots(g.slots()); err != nil {
+			return fmt.Errorf("cannot register @%s: %w", n.SyntaxType(), err)
+		}
+	}
+	return nil
+}
+
+// NewRegistry creates a registry containing the given nodes, each registered under its SyntaxType.
+// It returns a DuplicateNodeError if two nodes have the same SyntaxType.
+func NewRegistry(nodes ...SemNode) (*Registry, error) {
+	r := &Registry{
+		nodes:   make(map[string]registryEntry),
+		aliases: make(map[string]string),
+	}
+	for _, n := range nodes {
+		if err := r.Register(n); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// MustNewRegistry is like NewRegistry, but panics if there is an error.
+// It is intended for registries that are created in package level variables.
+func MustNewRegistry(nodes ...SemNode) *Registry {
+	r, err := NewRegistry(nodes...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// DuplicateNodeError is returned when registering a name that is already used by a node or alias in a Registry.
+type DuplicateNodeError struct {
+	Name string
+}
+
+// Error implements the error interface.
+func (e *DuplicateNodeError) Error() string {
+	return fmt.Sprintf("'%s' is already registered", e.Name)
+}
+
+// Register adds the node under its SyntaxType, returning a DuplicateNodeError if the name is already used.
+// Each parsed node is constructed from the registered node, see CloneableSemNode.
+func (r *Registry) Register(n SemNode) error {
+	return r.RegisterAs(n.SyntaxType(), n)
+}
+
+// RegisterAs adds the node under the given name, returning a DuplicateNodeError if the name is already used.
+func (r *Registry) RegisterAs(name string, n SemNode) error {
+	if r.has(name) {
+		return &DuplicateNodeError{Name: name}
+	}
+	e, err := newRegistryEntry(n)
+	if err != nil {
+		return err
+	}
+	r.nodes[name] = e
+	return nil
+}
+
+// RegisterFactory adds a node that is constructed by calling factory, under the SyntaxType of the nodes that it returns.
+// The factory is called once when registering, and once for every object that is parsed.
+// It returns a DuplicateNodeError if the name is already used.
+func (r *Registry) RegisterFactory(factory func() SemNode) error {
+	proto := factory()
+	name := proto.SyntaxType()
+	if r.has(name) {
+		return &DuplicateNodeError{Name: name}
+	}
+	if err := checkNode(proto); err != nil {
+		return err
+	}
+	r.nodes[name] = registryEntry{proto: proto, construct: factory}
+	return nil
+}
+
+// Override adds the node under its SyntaxType, replacing any node or alias that already uses that name.
+// It only returns an error if the node cannot be constructed, see CloneableSemNode.
+func (r *Registry) Override(n SemNode) error {
+	e, err := newRegistryEntry(n)
+	if err != nil {
+		return err
+	}
+	delete(r.aliases, n.SyntaxType())
+	r.nodes[n.SyntaxType()] = e
+	return nil
+}
+
+// RegisterPack adds the nodes under the given namespace, so a node with the SyntaxType 'grid' in the namespace 'gallery'
+// is used as '@gallery.grid'. It returns a DuplicateNodeError if any name is already used, in which case no nodes are added.
+func (r *Registry) RegisterPack(namespace string, nodes ...SemNode) error {
+	entries := make(map[string]registryEntry, len(nodes))
+	for _, n := range nodes {
+		name := namespace + "." + n.SyntaxType()
+		if _, ok := entries[name]; ok || r.has(name) {
+			return &DuplicateNodeError{Name: name}
+		}
+		e, err := newRegistryEntry(n)
+		if err != nil {
+			return err
+		}
+		entries[name] = e
+	}
+	for name, e := range entries {
+		r.nodes[name] = e
+	}
+	return nil
+}
+
+// Alias makes alias another name for the node registered as target, for example '@b' for '@bold'.
+// The target must be registered, and the alias must not already be used.
+func (r *Registry) Alias(alias, target string) error {
+	if r.has(alias) {
+		return &DuplicateNodeError{Name: alias}
+	}
+	if _, ok := r.nodes[target]; !ok {
+		return fmt.Errorf("cannot alias '%s' to '%s' as it is not registered", alias, target)
+	}
+	r.aliases[alias] = target
+	return nil
+}
+
+// Remove removes the node or alias with the given name, along with any aliases of it.
+// It returns false if the name was not registered.
+func (r *Registry) Remove(name string) bool {
+	if _, ok := r.aliases[name]; ok {
+		delete(r.aliases, name)
+		return true
+	}
+	if _, ok := r.nodes[name]; !ok {
+		return false
+	}
+	delete(r.nodes, name)
+	for alias, target := range r.aliases {
+		if target == name {
+			delete(r.aliases, alias)
+		}
+	}
+	return true
+}
+
+// Lookup returns the registered node for the given name, following aliases.
+// The returned node should not be parsed into, use New for that.
+func (r *Registry) Lookup(name string) (SemNode, bool) {
+	e, ok := r.nodes[r.canonical(name)]
+	return e.proto, ok
+}
+
+// New constructs a new node for the given name, following aliases, that can be parsed into.
+func (r *Registry) New(name string) (SemNode, bool) {
+	e, ok := r.nodes[r.canonical(name)]
+	if !ok {
+		return nil, false
+	}
+	return e.construct(), true
+}
+
+// Names returns all registered names, including aliases, in sorted order.
+func (r *Registry) Names() []string {
+	res := make([]string, 0, len(r.nodes)+len(r.aliases))
+	for name := range r.nodes {
+		res = append(res, name)
+	}
+	for alias := range r.aliases {
+		res = append(res, alias)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Aliases returns a copy of the map from each alias to the name that it refers to.
+func (r *Registry) Aliases() map[string]string {
+	res := make(map[string]string, len(r.aliases))
+	for alias, target := range r.aliases {
+		res[alias] = target
+	}
+	return res
+}
+
+// Clone returns a copy of the registry, which can be modified without affecting the original.
+func (r *Registry) Clone() *Registry {
+	c := &Registry{
+		nodes:   make(map[string]registryEntry, len(r.nodes)),
+		aliases: r.Aliases(),
+	}
+	for name, e := range r.nodes {
+		c.nodes[name] = e
+	}
+	return c
+}
+
+// Extend adds all nodes and aliases from other to this registry.
+// It returns a DuplicateNodeError if any name is used in both, in which case nothing is added.
+func (r *Registry) Extend(other *Registry) error {
+	for _, name := range other.Names() {
+		if r.has(name) {
+			return &DuplicateNodeError{Name: name}
+		}
+	}
+	for name, e := range other.nodes {
+		r.nodes[name] = e
+	}
+	for alias, target := range other.aliases {
+		r.aliases[alias] = target
+	}
+	return nil
+}
+
+// Parse parses the given syntax tree into a semantics tree using the nodes in the registry. See ParseSem.
+func (r *Registry) Parse(node any, opts ...SemOption) (SemNode, error) {
+	return parseSemWithRegistry(node, r, opts)
+}
+
+// canonical returns the name that an alias refers to, or the name itself if it is not an alias.
+func (r *Registry) canonical(name string) string {
+	if target, ok := r.aliases[name]; ok {
+		return target
+	}
+	return name
+}
+
+// has returns true if the name is used by a node or alias.
+func (r *Registry) has(name string) bool {
+	_, isNode := r.nodes[name]
+	_, isAlias := r.aliases[name]
+	return isNode || isAlias
+}
@@ -0,0 +1,248 @@
+package obtext
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testGrid struct {
+	SingleArgSemNode
+	// Columns is configuration that is set on the registered node.
+	Columns int
+}
+
+func (*testGrid) SyntaxType() string { return "grid" }
+
+// testValueNode is not a pointer to a struct, so it cannot be registered without a factory.
+type testValueNode string
+
+func (testValueNode) SyntaxType() string { return "value" }
+
+func (testValueNode) ParseArgs(args []*ContentBlockSemNode) error { return nil }
+
+func (testValueNode) Children() []SemNode { return nil }
+
+func mustNewRegistry(t testing.TB, nodes ...SemNode) *Registry {
+	t.Helper()
+	reg, err := NewRegistry(nodes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func TestRegistryDuplicates(t *testing.T) {
+	_, err := NewRegistry(&testPara{}, &testBold{}, &testPara{})
+	var dup *DuplicateNodeError
+	if !errors.As(err, &dup) || dup.Name != "para" {
+		t.Errorf("expected a duplicate para, got %v", err)
+	}
+	reg := mustNewRegistry(t, &testPara{})
+	if err := reg.RegisterAs("para", &testBold{}); !errors.As(err, &dup) {
+		t.Errorf("expected a duplicate, got %v", err)
+	}
+	if err := reg.Alias("para", "para"); !errors.As(err, &dup) {
+		t.Errorf("expected an alias that is already a node to be a duplicate, got %v", err)
+	}
+}
+
+func TestRegistryOverride(t *testing.T) {
+	reg := mustNewRegistry(t, &testPara{}, &testBold{})
+	if err := reg.Alias("grid", "para"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Override(&testGrid{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reg.Aliases()["grid"]; ok {
+		t.Errorf("the overridden alias was kept")
+	}
+	if n, _ := reg.Lookup("grid"); !isType[*testGrid](n) {
+		t.Errorf("got %T, want the overriding node", n)
+	}
+}
+
+func TestRegistryAliases(t *testing.T) {
+	reg := mustNewRegistry(t, &testDoc{}, &testBold{})
+	if err := reg.Alias("b", "bold"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Alias("x", "missing"); err == nil {
+		t.Errorf("expected an error when aliasing a name that is not registered")
+	}
+	sem, err := reg.Parse(mustParseSyn(t, "@doc{@b{hi}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isType[*testBold](body(sem)[0]) {
+		t.Errorf("the alias was not parsed as @bold: %T", body(sem)[0])
+	}
+	if got := reg.Names(); !reflect.DeepEqual(got, []string{"b", "bold", "doc"}) {
+		t.Errorf("got names %v", got)
+	}
+	// Removing a node removes its aliases
+	if !reg.Remove("bold") || reg.Remove("bold") {
+		t.Errorf("expected bold to be removed exactly once")
+	}
+	if got := reg.Names(); !reflect.DeepEqual(got, []string{"doc"}) {
+		t.Errorf("got names %v after removing bold", got)
+	}
+}
+
+func TestRegistryPacks(t *testing.T) {
+	reg := mustNewRegistry(t, &testDoc{})
+	if err := reg.RegisterPack("gallery", &testGrid{}, &testBold{}); err != nil {
+		t.Fatal(err)
+	}
+	sem, err := reg.Parse(mustParseSyn(t, "@doc{@gallery.grid{a}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isType[*testGrid](body(sem)[0]) {
+		t.Errorf("got %T, want a grid", body(sem)[0])
+	}
+	// A pack is added entirely or not at all
+	err = reg.RegisterPack("gallery", &testPara{}, &testGrid{})
+	var dup *DuplicateNodeError
+	if !errors.As(err, &dup) || dup.Name != "gallery.grid" {
+		t.Errorf("expected gallery.grid to be a duplicate, got %v", err)
+	}
+	if _, ok := reg.Lookup("gallery.para"); ok {
+		t.Errorf("part of a failed pack was registered")
+	}
+}
+
+func TestRegistryConstructsNewNodes(t *testing.T) {
+	proto := &testGrid{Columns: 3}
+	reg := mustNewRegistry(t, &testDoc{}, proto)
+	sem, err := reg.Parse(mustParseSyn(t, "@doc{@grid{a} @grid{b}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := body(sem)[0].(*testGrid), body(sem)[1].(*testGrid)
+	if a == proto || a == b {
+		t.Errorf("the registered node was parsed into")
+	}
+	if a.Columns != 3 || b.Columns != 3 {
+		t.Errorf("the configuration of the registered node was not copied")
+	}
+	if textOf(a) != "a" || textOf(b) != "b" || proto.Content != nil {
+		t.Errorf("parsed nodes share state")
+	}
+}
+
+func TestRegistryFactories(t *testing.T) {
+	if _, err := NewRegistry(testValueNode("")); err == nil {
+		t.Errorf("expected an error registering a node that cannot be constructed")
+	}
+	reg := mustNewRegistry(t)
+	calls := 0
+	err := reg.RegisterFactory(func() SemNode {
+		calls++
+		return testValueNode("")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Parse(mustParseSyn(t, "@value")); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("the factory was called %d times, want once to register and once to parse", calls)
+	}
+	if err := reg.RegisterFactory(func() SemNode { return testValueNode("") }); err == nil {
+		t.Errorf("expected a duplicate factory to be rejected")
+	}
+}
+
+func TestRegistryCloneAndExtend(t *testing.T) {
+	base := mustNewRegistry(t, &testDoc{}, &testPara{})
+	clone := base.Clone()
+	if err := clone.Register(&testBold{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := base.Lookup("bold"); ok {
+		t.Errorf("registering on a clone changed the original")
+	}
+	extra := mustNewRegistry(t, &testLink{})
+	if err := extra.Alias("l", "link"); err != nil {
+		t.Fatal(err)
+	}
+	if err := base.Extend(extra); err != nil {
+		t.Fatal(err)
+	}
+	if got := base.Names(); !reflect.DeepEqual(got, []string{"doc", "l", "link", "para"}) {
+		t.Errorf("got names %v", got)
+	}
+	// Extending with a registry that shares a name adds nothing
+	if err := base.Extend(clone); err == nil {
+		t.Errorf("expected a duplicate error")
+	}
+	if _, ok := base.Lookup("bold"); ok {
+		t.Errorf("a failed extend added nodes")
+	}
+}
+
+// body returns the elements of the first arg of a single arg node.
+func body(n SemNode) []SemNode {
+	return n.Children()[0].Children()
+}
+
+func isType[T any](v any) bool {
+	_, ok := v.(T)
+	return ok
+}
+
+func TestParseSemUsesCurrentSemantics(t *testing.T) {
+	// Each call uses the semantics it is given, even if the same slice was used before and has since been changed
+	semantics := []SemNode{&testGrid{Columns: 2}}
+	syn := mustParseSyn(t, "@grid{x}")
+	sem, err := ParseSem(syn, semantics)
+	if err != nil || sem.(*testGrid).Columns != 2 {
+		t.Fatalf("got %+v, %v", sem, err)
+	}
+	semantics[0] = &testGrid{Columns: 3}
+	if sem, err = ParseSem(syn, semantics); err != nil || sem.(*testGrid).Columns != 3 {
+		t.Errorf("got %+v, %v after replacing the node", sem, err)
+	}
+	semantics[0].(*testGrid).Columns = 4
+	if sem, err = ParseSem(syn, semantics); err != nil || sem.(*testGrid).Columns != 4 {
+		t.Errorf("got %+v, %v after changing the node", sem, err)
+	}
+}
+
+func BenchmarkParseSem(b *testing.B) {
+	syn := mustParseSyn(b, benchmarkSource(100))
+	semantics := testSemantics()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseSem(syn, semantics); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRegistryParse(b *testing.B) {
+	syn := mustParseSyn(b, benchmarkSource(100))
+	reg := mustNewRegistry(b, testSemantics()...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := reg.Parse(syn); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkSource returns a document with the given number of paragraphs.
+func benchmarkSource(paras int) string {
+	sb := &strings.Builder{}
+	sb.WriteString("@doc{\n")
+	for i := 0; i < paras; i++ {
+		fmt.Fprintf(sb, "@para{Paragraph %d has @bold{bold} text, @code{go}{some code} and a @link{https://example.com}{link}.}\n", i)
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
@@ -0,0 +1,444 @@
+package obtext
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ParseTaggedArgs parses the args of an object into the fields of node, which must be a pointer to a struct,
+// according to the `obt` struct tags on its fields. It can be used to implement the ParseArgs method of a SemNode:
+//
+//	type HeadingSemNode struct {
+//		Title *obtext.ContentBlockSemNode `obt:"0,content"`
+//		Level int                         `obt:"1,int,optional,default=1"`
+//	}
+//
+//	func (h *HeadingSemNode) ParseArgs(args []*obtext.ContentBlockSemNode) error {
+//		return obtext.ParseTaggedArgs(h, args)
+//	}
+//
+// The tag has the form `obt:"<index>,<kind>[,<option>...]"`.
+// The index is the position of the argument, starting at 0. The kind decides what the argument must contain and the type of the field:
+//   - content: any content, into a *ContentBlockSemNode
+//   - text: a single piece of text, into a string
+//   - int: text that is an integer, into any int type
+//   - float: text that is a number, into any float type
+//   - bool: text that is true or false, into a bool
+//   - url: text that is a URL, into a *url.URL, url.URL or string
+//   - date: text that is a date, into a time.Time
+//   - enum: text that is one of a set of values, into a string
+//   - path: text that is a slash separated file path, into a string (the path is cleaned)
+//
+// The options are:
+//   - required, optional or variadic: see below, the default is required
+//   - default=<value>: the value of an optional argument when it is missing, which must be the last option as it may contain commas
+//   - match=<regexp>: the text of the argument must match this regular expression
+//   - values=<a|b|c>: the allowed values of an enum
+//   - format=<layout>: the time.Parse layout of a date, by default 2006-01-02
+//   - schemes=<a|b>: the allowed schemes of a url
+//
+// Commas inside an option (other than default) must be escaped as '\,'.
+// Arguments are required unless marked optional, in which case the default (or the zero value if there is no default) is used when it is missing.
+// A variadic argument must have the highest index, and its field is a slice that receives all remaining arguments.
+// Fields embedded in the struct are searched for tags too.
+// A panic occurs if the tags are invalid, as this is a programming error.
+//
+// When node is parsed by ParseSem, all non-content arguments are cast before ParseArgs is called,
+// and the result is stored in the CastValue of the ContentBlockSemNode. ParseTaggedArgs then uses this value instead of casting again.
+// The syntax tree is never changed, so it can be parsed again, such as with different semantics.
+func ParseTaggedArgs(node any, args []*ContentBlockSemNode) error {
+	v := reflect.ValueOf(node)
+	s := schemaFor(v.Type())
+	if err := checkArgCount(args, s.min, s.max); err != nil {
+		return err
+	}
+	v = v.Elem()
+	for _, f := range s.fields {
+		field := v.FieldByIndex(f.index)
+		if f.variadic {
+			var rest []*ContentBlockSemNode
+			if f.arg < len(args) {
+				rest = args[f.arg:]
+			}
+			slice := reflect.MakeSlice(field.Type(), len(rest), len(rest))
+			for i := range rest {
+				if err := f.set(slice.Index(i), args, f.arg+i); err != nil {
+					return err
+				}
+			}
+			field.Set(slice)
+			continue
+		}
+		if f.arg >= len(args) {
+			field.Set(f.def())
+			continue
+		}
+		if err := f.set(field, args, f.arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TaggedChildren returns all content arguments of node, which must be a pointer to a struct with `obt` tags, in argument order.
+// It can be used to implement the Children method of a SemNode alongside ParseTaggedArgs.
+func TaggedChildren(node any) []SemNode {
+	v := reflect.ValueOf(node)
+	s := schemaFor(v.Type())
+	v = v.Elem()
+	res := make([]SemNode, 0, len(s.fields))
+	for _, f := range s.fields {
+		if f.kind.name != "content" {
+			continue
+		}
+		field := v.FieldByIndex(f.index)
+		if f.variadic {
+			for i := 0; i < field.Len(); i++ {
+				res = append(res, field.Index(i).Interface().(*ContentBlockSemNode))
+			}
+			continue
+		}
+		if c := field.Interface().(*ContentBlockSemNode); c != nil {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// TaggedSetChild replaces the i-th node returned by TaggedChildren with child, which must be a *ContentBlockSemNode.
+// It can be used to implement the SetChild method of a MutableSemNode alongside ParseTaggedArgs.
+func TaggedSetChild(node any, i int, child SemNode) error {
+	block, ok := child.(*ContentBlockSemNode)
+	if !ok {
+		return fmt.Errorf("child %d must be a content block, got %T", i, child)
+	}
+	v := reflect.ValueOf(node)
+	s := schemaFor(v.Type())
+	v = v.Elem()
+	for _, f := range s.fields {
+		if f.kind.name != "content" {
+			continue
+		}
+		field := v.FieldByIndex(f.index)
+		if f.variadic {
+			if i < field.Len() {
+				field.Index(i).Set(reflect.ValueOf(block))
+				return nil
+			}
+			i -= field.Len()
+			continue
+		}
+		if field.IsNil() {
+			continue
+		}
+		if i == 0 {
+			field.Set(reflect.ValueOf(block))
+			return nil
+		}
+		i--
+	}
+	return fmt.Errorf("child index out of range")
+}
+
+// argSchema is the parsed form of all `obt` tags on a struct.
+type argSchema struct {
+	fields []schemaField
+	min    int
+	max    int
+}
+
+// schemaField is the parsed form of a single `obt` tag.
+type schemaField struct {
+	name     string
+	index    []int
+	arg      int
+	kind     argKind
+	required bool
+	variadic bool
+	// elemType is the type that each argument is cast to, which is the element type for variadic fields.
+	elemType reflect.Type
+	// def returns the value used when an optional argument is missing.
+	def func() reflect.Value
+	// The following are set by options, and only used by some kinds.
+	match   *regexp.Regexp
+	values  []string
+	format  string
+	schemes []string
+}
+
+// set parses args[i] into the given value according to the kind of the field.
+func (f *schemaField) set(v reflect.Value, args []*ContentBlockSemNode, i int) error {
+	if f.kind.cast == nil {
+		v.Set(reflect.ValueOf(args[i]))
+		return nil
+	}
+	if args[i].CastValue != nil {
+		if cv := reflect.ValueOf(args[i].CastValue); cv.Type().AssignableTo(v.Type()) {
+			v.Set(cv)
+			return nil
+		}
+	}
+	cast, err := f.castArg(args, i)
+	if err != nil {
+		return err
+	}
+	v.Set(cast)
+	return nil
+}
+
+// castArg casts the text of args[i] according to the kind and options of the field.
+func (f *schemaField) castArg(args []*ContentBlockSemNode, i int) (reflect.Value, error) {
+	text, err := textArg(args, i)
+	if err != nil {
+		err.(*ArgTypeError).Expected = f.expected()
+		return reflect.Value{}, err
+	}
+	if f.match != nil && !f.match.MatchString(text) {
+		return reflect.Value{}, &ArgTypeError{Arg: i, Expected: f.expected(), Got: strconv.Quote(text)}
+	}
+	cast, err := f.kind.cast(text, f, f.elemType)
+	if err != nil {
+		return reflect.Value{}, &ArgTypeError{Arg: i, Expected: f.expected(), Got: strconv.Quote(text)}
+	}
+	return cast, nil
+}
+
+// expected describes what an argument for this field must contain, for use in an ArgTypeError.
+func (f *schemaField) expected() string {
+	e := f.kind.expected(f)
+	if f.match != nil {
+		e += " matching " + f.match.String()
+	}
+	return e
+}
+
+// castArgs casts the parsed arguments of an object according to the `obt` tags of its semantic node, if it has any,
+// storing the result in their CastValue.
+// Arguments that are not covered by the tags, or that are content, are left alone.
+func castArgs(sem SemNode, args []*ContentBlockSemNode) error {
+	s, ok := taggedSchemaFor(reflect.TypeOf(sem))
+	if !ok {
+		return nil
+	}
+	for i := range args {
+		f := s.fieldForArg(i)
+		if f == nil || f.kind.cast == nil {
+			continue
+		}
+		cast, err := f.castArg(args, i)
+		if err != nil {
+			return err
+		}
+		args[i].CastValue = cast.Interface()
+	}
+	return nil
+}
+
+// fieldForArg returns the field that the i-th argument is parsed into, or nil if there is none.
+func (s *argSchema) fieldForArg(i int) *schemaField {
+	if i < len(s.fields) {
+		return &s.fields[i]
+	}
+	if len(s.fields) > 0 && s.fields[len(s.fields)-1].variadic {
+		return &s.fields[len(s.fields)-1]
+	}
+	return nil
+}
+
+var schemaCache sync.Map
+
+// schemaFor returns the schema for the given pointer to struct type, parsing and caching it if required.
+func schemaFor(t reflect.Type) *argSchema {
+	if s, ok := schemaCache.Load(t); ok {
+		return s.(*argSchema)
+	}
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("obt tags can only be used on a pointer to a struct, got %s", t))
+	}
+	s := parseSchema(t.Elem())
+	schemaCache.Store(t, s)
+	return s
+}
+
+// taggedSchemaFor is like schemaFor, but returns false instead of panicking if the type is not a pointer to a struct,
+// and also returns false if the struct has no `obt` tags.
+func taggedSchemaFor(t reflect.Type) (*argSchema, bool) {
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+	s := schemaFor(t)
+	return s, len(s.fields) > 0
+}
+
+func parseSchema(t reflect.Type) *argSchema {
+	s := &argSchema{}
+	collectSchemaFields(t, nil, s)
+	byArg := make(map[int]schemaField)
+	maxArg := -1
+	for _, f := range s.fields {
+		if other, ok := byArg[f.arg]; ok {
+			panic(fmt.Sprintf("obt tags on %s: fields %s and %s both use arg %d", t, other.name, f.name, f.arg))
+		}
+		byArg[f.arg] = f
+		maxArg = max(maxArg, f.arg)
+	}
+	s.max = maxArg + 1
+	seenOptional := false
+	for i := 0; i <= maxArg; i++ {
+		f, ok := byArg[i]
+		if !ok {
+			panic(fmt.Sprintf("obt tags on %s: no field for arg %d", t, i))
+		}
+		if f.variadic {
+			if i != maxArg {
+				panic(fmt.Sprintf("obt tags on %s: variadic field %s must have the highest arg", t, f.name))
+			}
+			s.max = -1
+			continue
+		}
+		if f.required {
+			if seenOptional {
+				panic(fmt.Sprintf("obt tags on %s: required field %s comes after an optional field", t, f.name))
+			}
+			s.min++
+		} else {
+			seenOptional = true
+		}
+	}
+	// Keep the fields in arg order, so children are returned in the order they were written
+	sortedFields := make([]schemaField, 0, len(s.fields))
+	for i := 0; i <= maxArg; i++ {
+		sortedFields = append(sortedFields, byArg[i])
+	}
+	s.fields = sortedFields
+	return s
+}
+
+// collectSchemaFields adds a schemaField to s for every tagged field in t, including in embedded structs.
+func collectSchemaFields(t reflect.Type, index []int, s *argSchema) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		tag, ok := sf.Tag.Lookup("obt")
+		if !ok {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				collectSchemaFields(sf.Type, fieldIndex, s)
+			}
+			continue
+		}
+		s.fields = append(s.fields, parseSchemaField(t, sf, fieldIndex, tag))
+	}
+}
+
+func parseSchemaField(t reflect.Type, sf reflect.StructField, index []int, tag string) schemaField {
+	invalid := func(format string, args ...any) {
+		panic(fmt.Sprintf("obt tag on %s.%s: ", t, sf.Name) + fmt.Sprintf(format, args...))
+	}
+	parts := splitTag(tag)
+	if len(parts) < 2 {
+		invalid("expected at least an index and a kind")
+	}
+	arg, err := strconv.Atoi(parts[0])
+	if err != nil || arg < 0 {
+		invalid("invalid index '%s'", parts[0])
+	}
+	kind, ok := argKinds[parts[1]]
+	if !ok {
+		invalid("unknown kind '%s'", parts[1])
+	}
+	f := schemaField{name: sf.Name, index: index, arg: arg, kind: kind, required: true, format: "2006-01-02"}
+	var def *string
+	for i := 2; i < len(parts); i++ {
+		switch {
+		case parts[i] == "required":
+			f.required = true
+		case parts[i] == "optional":
+			f.required = false
+		case parts[i] == "variadic":
+			f.variadic = true
+			f.required = false
+		case strings.HasPrefix(parts[i], "default="):
+			// The default is always last, so that it can contain commas
+			d := unescapeTag(strings.Join(parts[i:], ",")[len("default="):])
+			def = &d
+			i = len(parts)
+		case strings.HasPrefix(parts[i], "match="):
+			// The regexp is not unescaped, as '\,' is a valid way to match a comma anyway
+			reg, err := regexp.Compile(strings.TrimPrefix(parts[i], "match="))
+			if err != nil {
+				invalid("invalid match regexp: %v", err)
+			}
+			f.match = reg
+		case strings.HasPrefix(parts[i], "values="):
+			f.values = strings.Split(unescapeTag(strings.TrimPrefix(parts[i], "values=")), "|")
+		case strings.HasPrefix(parts[i], "format="):
+			f.format = unescapeTag(strings.TrimPrefix(parts[i], "format="))
+		case strings.HasPrefix(parts[i], "schemes="):
+			f.schemes = strings.Split(unescapeTag(strings.TrimPrefix(parts[i], "schemes=")), "|")
+		default:
+			invalid("unknown option '%s'", parts[i])
+		}
+	}
+	elemType := sf.Type
+	if f.variadic {
+		if sf.Type.Kind() != reflect.Slice {
+			invalid("variadic field must be a slice")
+		}
+		elemType = sf.Type.Elem()
+	}
+	if !kind.accepts(elemType) {
+		invalid("kind %s cannot be stored in a %s", kind.name, elemType)
+	}
+	f.elemType = elemType
+	if kind.name == "enum" && len(f.values) == 0 {
+		invalid("enum must have values")
+	}
+	zero := reflect.Zero(sf.Type)
+	f.def = func() reflect.Value { return zero }
+	if def != nil {
+		if f.required || f.variadic {
+			invalid("only optional fields can have a default")
+		}
+		if kind.cast == nil {
+			// Content is a pointer, so a new block is created each time to avoid nodes sharing it
+			text := *def
+			f.def = func() reflect.Value {
+				return reflect.ValueOf(&ContentBlockSemNode{Elements: []SemNode{&TextSemNode{Text: text}}})
+			}
+		} else {
+			v, err := kind.cast(*def, &f, sf.Type)
+			if err != nil {
+				invalid("invalid default: %v", err)
+			}
+			f.def = func() reflect.Value { return v }
+		}
+	}
+	return f
+}
+
+// splitTag splits an `obt` tag on commas, except those escaped with a backslash.
+// The escaping backslashes are kept, see unescapeTag.
+func splitTag(tag string) []string {
+	parts := make([]string, 0)
+	start := 0
+	for i := 0; i < len(tag); i++ {
+		if tag[i] == '\\' {
+			i++
+			continue
+		}
+		if tag[i] == ',' {
+			parts = append(parts, tag[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tag[start:])
+}
+
+// unescapeTag removes the backslashes from escaped commas in part of an `obt` tag.
+func unescapeTag(s string) string {
+	return strings.ReplaceAll(s, "\\,", ",")
+}
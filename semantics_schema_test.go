@@ -0,0 +1,199 @@
+package obtext
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testHeading struct {
+	Title *ContentBlockSemNode `obt:"0,content"`
+	Level int                  `obt:"1,int,optional,default=2"`
+	Label string               `obt:"2,text,optional"`
+}
+
+func (*testHeading) SyntaxType() string { return "heading" }
+
+func (h *testHeading) ParseArgs(args []*ContentBlockSemNode) error {
+	return ParseTaggedArgs(h, args)
+}
+
+func (h *testHeading) Children() []SemNode {
+	return TaggedChildren(h)
+}
+
+// textArgs returns a content block of text for each string, like the args ParseSem passes to ParseArgs.
+func textArgs(texts ...string) []*ContentBlockSemNode {
+	args := make([]*ContentBlockSemNode, len(texts))
+	for i, t := range texts {
+		args[i] = &ContentBlockSemNode{Elements: []SemNode{&TextSemNode{Text: t}}}
+	}
+	return args
+}
+
+func TestParseTaggedArgs(t *testing.T) {
+	h := &testHeading{}
+	if err := ParseTaggedArgs(h, textArgs("Title", "3", "intro")); err != nil {
+		t.Fatal(err)
+	}
+	if textOf(h.Title) != "Title" || h.Level != 3 || h.Label != "intro" {
+		t.Errorf("got %+v", h)
+	}
+}
+
+func TestParseTaggedArgsDefaults(t *testing.T) {
+	h := &testHeading{Level: 10, Label: "old"}
+	if err := ParseTaggedArgs(h, textArgs("Title")); err != nil {
+		t.Fatal(err)
+	}
+	if h.Level != 2 || h.Label != "" {
+		t.Errorf("missing optional args were not set to their defaults: %+v", h)
+	}
+}
+
+func TestParseTaggedArgsErrors(t *testing.T) {
+	var countErr *ArgCountError
+	err := ParseTaggedArgs(&testHeading{}, textArgs())
+	if !errors.As(err, &countErr) || countErr.Min != 1 || countErr.Max != 3 || countErr.Got != 0 {
+		t.Errorf("expected an ArgCountError for between 1 and 3 args, got %v", err)
+	}
+	err = ParseTaggedArgs(&testHeading{}, textArgs("a", "b", "c", "d"))
+	if !errors.As(err, &countErr) || countErr.Got != 4 {
+		t.Errorf("expected an ArgCountError for 4 args, got %v", err)
+	}
+	var typeErr *ArgTypeError
+	err = ParseTaggedArgs(&testHeading{}, textArgs("a", "two"))
+	if !errors.As(err, &typeErr) || typeErr.Arg != 1 || typeErr.Expected != "an integer" || typeErr.Got != `"two"` {
+		t.Errorf("expected an ArgTypeError for arg 1, got %v", err)
+	}
+	args := textArgs("a", "b")
+	args[1].Elements = append(args[1].Elements, &testBold{})
+	err = ParseTaggedArgs(&testHeading{}, args)
+	if !errors.As(err, &typeErr) || typeErr.Got != "2 elements" {
+		t.Errorf("expected an ArgTypeError for mixed content, got %v", err)
+	}
+}
+
+func TestParseTaggedArgsVariadic(t *testing.T) {
+	l := &ListArgSemNode{}
+	if err := ParseTaggedArgs(l, textArgs("a", "b", "c")); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Contents) != 3 || textOf(l.Contents[2]) != "c" {
+		t.Errorf("got %+v", l.Contents)
+	}
+	if err := ParseTaggedArgs(l, textArgs()); err != nil || len(l.Contents) != 0 {
+		t.Errorf("expected no items and no error, got %v and %v", l.Contents, err)
+	}
+	e := &testFigure{}
+	if err := ParseTaggedArgs(e, textArgs("main", "x", "y")); err != nil {
+		t.Fatal(err)
+	}
+	if textOf(e.Content) != "main" || len(e.Extras) != 2 {
+		t.Errorf("got %+v", e)
+	}
+}
+
+func TestTaggedChildren(t *testing.T) {
+	e := &testFigure{}
+	if err := ParseTaggedArgs(e, textArgs("a", "b", "c")); err != nil {
+		t.Fatal(err)
+	}
+	children := TaggedChildren(e)
+	if len(children) != 3 || textOf(children[0]) != "a" || textOf(children[2]) != "c" {
+		t.Fatalf("got children %v", children)
+	}
+	replacement := textArgs("z")[0]
+	if err := TaggedSetChild(e, 2, replacement); err != nil {
+		t.Fatal(err)
+	}
+	if e.Extras[1] != replacement {
+		t.Errorf("child 2 was not replaced")
+	}
+	if err := TaggedSetChild(e, 3, replacement); err == nil {
+		t.Errorf("expected an error for an out of range child")
+	}
+	if err := TaggedSetChild(e, 0, &TextSemNode{}); err == nil {
+		t.Errorf("expected an error for a child that is not a content block")
+	}
+	h := &testHeading{}
+	if err := ParseTaggedArgs(h, textArgs("Title", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if children := TaggedChildren(h); len(children) != 1 {
+		t.Errorf("only content args should be children, got %v", children)
+	}
+}
+
+func TestParseSemWithTags(t *testing.T) {
+	sem, err := ParseSem(mustParseSyn(t, "@heading{Intro}{4}"), []SemNode{&testHeading{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := sem.(*testHeading); textOf(h.Title) != "Intro" || h.Level != 4 {
+		t.Errorf("got %+v", h)
+	}
+}
+
+func TestInvalidTagsPanic(t *testing.T) {
+	cases := []struct {
+		name string
+		node any
+		msg  string
+	}{
+		{"not a struct", new(int), "pointer to a struct"},
+		{"duplicate arg", &struct {
+			A string `obt:"0,text"`
+			B string `obt:"0,text"`
+		}{}, "both use arg 0"},
+		{"missing arg", &struct {
+			A string `obt:"1,text"`
+		}{}, "no field for arg 0"},
+		{"required after optional", &struct {
+			A string `obt:"0,text,optional"`
+			B string `obt:"1,text"`
+		}{}, "comes after an optional field"},
+		{"variadic not last", &struct {
+			A []string `obt:"0,text,variadic"`
+			B string   `obt:"1,text,optional"`
+		}{}, "must have the highest arg"},
+		{"unknown kind", &struct {
+			A string `obt:"0,number"`
+		}{}, "unknown kind"},
+		{"wrong field type", &struct {
+			A int `obt:"0,text"`
+		}{}, "cannot be stored"},
+		{"default on required", &struct {
+			A string `obt:"0,text,default=x"`
+		}{}, "only optional fields can have a default"},
+		{"invalid default", &struct {
+			A int `obt:"0,int,optional,default=x"`
+		}{}, "invalid default"},
+		{"unknown option", &struct {
+			A string `obt:"0,text,sometimes"`
+		}{}, "unknown option"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Fatalf("expected a panic")
+				}
+				if msg := r.(string); !strings.Contains(msg, c.msg) {
+					t.Errorf("got panic %q, want it to contain %q", msg, c.msg)
+				}
+			}()
+			schemaFor(reflect.TypeOf(c.node))
+		})
+	}
+}
+
+func TestSplitTag(t *testing.T) {
+	got := splitTag(`0,enum,values=a\,b|c,default=x,y`)
+	want := []string{"0", "enum", `values=a\,b|c`, "default=x", "y"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
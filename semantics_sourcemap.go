@@ -0,0 +1,32 @@
+package obtext
+
+// SourceMap records where each node of a semantic tree was parsed from, so that later stages, such as rendering, can report the position of a node.
+// Fill it in by passing WithSourceMap to ParseSem.
+type SourceMap struct {
+	origins map[SemNode]nodeOrigin
+}
+
+// WithSourceMap makes ParseSem record where each node was parsed from in the source map, once parsing succeeds.
+func WithSourceMap(m *SourceMap) SemOption {
+	return func(p *semParser) {
+		p.sourceMap = m
+	}
+}
+
+// Pos returns the position in the source of the object or text that the node was parsed from.
+// It is not valid for nodes that were created by a pass, for ContentBlockSemNodes, or if the map is nil.
+func (m *SourceMap) Pos(n SemNode) Position {
+	if m == nil {
+		return Position{}
+	}
+	return m.origins[n].pos
+}
+
+// Path returns the path of objects from the root to the object that the node was parsed from.
+// It is nil for nodes that were created by a pass, for ContentBlockSemNodes, or if the map is nil.
+func (m *SourceMap) Path(n SemNode) ObjectPath {
+	if m == nil {
+		return nil
+	}
+	return m.origins[n].path
+}
@@ -0,0 +1,74 @@
+package obtext
+
+import (
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	m := &SourceMap{}
+	sem, err := parseTestSem(t, "@doc{\n\t@para{a @bold{b}}\n\t@para{c}\n}", WithSourceMap(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paras := FindAll[*testPara](sem)
+	bold := FindAll[*testBold](sem)[0]
+	cases := []struct {
+		node SemNode
+		pos  string
+		path string
+	}{
+		{sem, "1:1", "doc"},
+		{paras[0], "2:2", "doc > para[0]"},
+		{paras[1], "3:2", "doc > para[1]"},
+		{bold, "2:10", "doc > para[0] > bold[1]"},
+		// Indexes count text as well as objects, and text has the path of the object it is in
+		{body(bold)[0], "2:16", "doc > para[0] > bold[1]"},
+	}
+	for _, c := range cases {
+		if got := m.Pos(c.node).String(); got != c.pos {
+			t.Errorf("%T: got position %s, want %s", c.node, got, c.pos)
+		}
+		if got := m.Path(c.node).String(); got != c.path {
+			t.Errorf("%T: got path %s, want %s", c.node, got, c.path)
+		}
+	}
+	// Content blocks are not parsed from an object or text
+	if m.Pos(paras[0].Content).IsValid() || m.Path(paras[0].Content) != nil {
+		t.Errorf("a content block has a position")
+	}
+}
+
+func TestSourceMapPasses(t *testing.T) {
+	// Nodes made by a pass have no position
+	m := &SourceMap{}
+	added := &TextSemNode{Text: "added"}
+	sem, err := parseTestSem(t, "@doc{a}", WithSourceMap(m), WithPasses(func(ctx *PassContext) error {
+		ctx.Root = &ContentBlockSemNode{Elements: []SemNode{ctx.Root, added}}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Pos(added).IsValid() || m.Path(added) != nil {
+		t.Errorf("a node added by a pass has a position")
+	}
+	if doc := sem.Children()[0]; m.Pos(doc).String() != "1:1" {
+		t.Errorf("the original root lost its position")
+	}
+}
+
+func TestSourceMapEmpty(t *testing.T) {
+	var m *SourceMap
+	n := &TextSemNode{Text: "a"}
+	if m.Pos(n).IsValid() || m.Path(n) != nil {
+		t.Errorf("a nil source map has a position")
+	}
+	// The map is only filled in if parsing succeeds
+	m = &SourceMap{}
+	if _, err := parseTestSem(t, "@doc{@foo}", WithSourceMap(m)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if m.origins != nil {
+		t.Errorf("the source map was filled in by a failed parse")
+	}
+}
@@ -0,0 +1,116 @@
+package obtext
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of suggestions given for an unknown object.
+const maxSuggestions = 3
+
+// suggestTypes returns the known syntax types that are closest to the given unknown type by edit distance, closest first.
+// Only types that are close enough to plausibly be a typo are returned.
+func suggestTypes(unknown string, known []string) []string {
+	type candidate struct {
+		name string
+		dist int
+	}
+	// Allow roughly one mistake for every three characters, but always allow at least one
+	maxDist := len(unknown) / 3
+	if maxDist < 1 {
+		maxDist = 1
+	}
+	candidates := make([]candidate, 0)
+	for _, k := range known {
+		d := editDistance(strings.ToLower(unknown), strings.ToLower(k))
+		if d <= maxDist {
+			candidates = append(candidates, candidate{k, d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].dist != candidates[j].dist {
+			return candidates[i].dist < candidates[j].dist
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+	res := make([]string, len(candidates))
+	for i, c := range candidates {
+		res[i] = c.name
+	}
+	return res
+}
+
+// editDistance returns the Damerau-Levenshtein (optimal string alignment) distance between a and b,
+// so a swap of two adjacent characters counts as a single edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between the first i runes of a and the first j runes of b
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// hintFor returns a hint about a common mistake that may have caused the error, or an empty string if there is none.
+func hintFor(objType string, err error) string {
+	var countErr *ArgCountError
+	var typeErr *ArgTypeError
+	switch {
+	case errors.As(err, &countErr):
+		err := countErr
+		if err.Max >= 0 && err.Got > err.Max {
+			// The most common cause of extra args is text in braces directly after an object, which is parsed as another arg
+			takes := pluralArgs(err.Max)
+			if err.Min != err.Max {
+				takes = fmt.Sprintf("at most %d arguments", err.Max)
+			}
+			return fmt.Sprintf("@%s takes %s, but braces after an object are always parsed as arguments, even after whitespace, so escape them as '\\{' and '\\}' if they are meant to be text", objType, takes)
+		}
+		if err.Got < err.Min {
+			needs := pluralArgs(err.Min)
+			if err.Min != err.Max {
+				needs = "at least " + needs
+			}
+			return fmt.Sprintf("@%s needs %s, written as @%s%s", objType, needs, objType, strings.Repeat("{...}", err.Min))
+		}
+	case errors.As(err, &typeErr):
+		err := typeErr
+		if strings.HasPrefix(err.Got, "object @") {
+			return fmt.Sprintf("arg %d must not contain objects, so escape the '@' as '\\@' if it is meant to be text", err.Arg)
+		}
+		if err.Got == "nothing" {
+			return fmt.Sprintf("arg %d is empty", err.Arg)
+		}
+	}
+	return ""
+}
+
+// pluralArgs returns '1 argument' or 'n arguments'.
+func pluralArgs(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
+}
@@ -0,0 +1,99 @@
+package obtext
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"bold", "bold", 0},
+		{"bold", "", 4},
+		{"itallic", "italic", 1},
+		{"subsecton", "subsection", 1},
+		{"bodl", "bold", 1},
+		{"para", "link", 4},
+	}
+	for _, c := range cases {
+		if got := editDistance(c.a, c.b); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestSuggestTypes(t *testing.T) {
+	known := []string{"bold", "italic", "section", "subsection", "para", "img", "link"}
+	cases := []struct {
+		unknown string
+		want    []string
+	}{
+		{"itallic", []string{"italic"}},
+		{"subsecton", []string{"subsection"}},
+		{"Bold", []string{"bold"}},
+		{"imgg", []string{"img"}},
+		{"zzzzzz", []string{}},
+	}
+	for _, c := range cases {
+		if got := suggestTypes(c.unknown, known); !slices.Equal(got, c.want) {
+			t.Errorf("suggestTypes(%q) = %v, want %v", c.unknown, got, c.want)
+		}
+	}
+}
+
+func TestUnknownObjectSuggestions(t *testing.T) {
+	_, err := parseTestSem(t, "@doc{@para{@bodl{x}}}")
+	var unknown *UnknownObjectError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected an *UnknownObjectError, got %T: %v", err, err)
+	}
+	if !slices.Equal(unknown.Suggestions, []string{"bold"}) {
+		t.Errorf("got suggestions %v, want [bold]", unknown.Suggestions)
+	}
+	if !strings.Contains(err.Error(), "did you mean @bold?") {
+		t.Errorf("error message does not include the suggestion: %q", err)
+	}
+}
+
+func TestHints(t *testing.T) {
+	cases := []struct {
+		src  string
+		hint string
+	}{
+		{"@doc{@para{a} {b}}", "@para takes 1 argument, but braces after an object are always parsed as arguments"},
+		{"@doc{@link{a}}", "@link needs 2 arguments, written as @link{...}{...}"},
+		{"@doc{@figure}", "@figure needs at least 1 argument, written as @figure{...}"},
+		{"@doc{@code{go}{@bold{x}}}", "arg 1 must not contain objects, so escape the '@' as '\\@'"},
+		{"@doc{@code{go}{}}", "arg 1 is empty"},
+	}
+	for _, c := range cases {
+		_, err := parseTestSem(t, c.src)
+		var semErr *SemError
+		if !errors.As(err, &semErr) {
+			t.Errorf("%s: expected a *SemError, got %T: %v", c.src, err, err)
+			continue
+		}
+		if !strings.HasPrefix(semErr.Hint, c.hint) {
+			t.Errorf("%s: got hint %q, want it to start with %q", c.src, semErr.Hint, c.hint)
+		}
+		if !strings.Contains(err.Error(), "(hint: "+semErr.Hint+")") {
+			t.Errorf("%s: error message does not include the hint: %q", c.src, err)
+		}
+	}
+}
+
+func TestNoHint(t *testing.T) {
+	_, err := parseTestSem(t, "@doc{@foo{}}")
+	var semErr *SemError
+	if !errors.As(err, &semErr) {
+		t.Fatalf("expected a *SemError, got %T: %v", err, err)
+	}
+	if semErr.Hint != "" || strings.Contains(err.Error(), "hint") {
+		t.Errorf("expected no hint, got %q", semErr.Hint)
+	}
+}
@@ -0,0 +1,88 @@
+package obtext
+
+import "testing"
+
+// The nodes below are a small markup-like set of semantics used throughout the tests of this package.
+
+type testDoc struct{ SingleArgSemNode }
+
+func (*testDoc) SyntaxType() string { return "doc" }
+
+type testPara struct{ SingleArgSemNode }
+
+func (*testPara) SyntaxType() string { return "para" }
+
+type testBold struct{ SingleArgSemNode }
+
+func (*testBold) SyntaxType() string { return "bold" }
+
+type testLink struct{ CaptionedLinkSemNode }
+
+func (*testLink) SyntaxType() string { return "link" }
+
+type testCode struct{ DualStringSemNode }
+
+func (*testCode) SyntaxType() string { return "code" }
+
+type testList struct{ ListArgSemNode }
+
+func (*testList) SyntaxType() string { return "list" }
+
+// testFigure has a caption followed by any number of extra args.
+type testFigure struct {
+	Content *ContentBlockSemNode   `obt:"0,content"`
+	Extras  []*ContentBlockSemNode `obt:"1,content,variadic"`
+}
+
+func (*testFigure) SyntaxType() string { return "figure" }
+
+func (f *testFigure) ParseArgs(args []*ContentBlockSemNode) error { return ParseTaggedArgs(f, args) }
+
+func (f *testFigure) Children() []SemNode { return TaggedChildren(f) }
+
+func (f *testFigure) SetChild(i int, n SemNode) error { return TaggedSetChild(f, i, n) }
+
+func (f *testFigure) Unparse() ([]*ContentBlockSemNode, error) { return TaggedUnparse(f) }
+
+// testSemantics returns the semantics used by most tests.
+func testSemantics() []SemNode {
+	return []SemNode{&testDoc{}, &testPara{}, &testBold{}, &testLink{}, &testCode{}, &testList{}, &testFigure{}}
+}
+
+// mustParseSyn parses the source into a syntax tree, failing the test if it is invalid.
+func mustParseSyn(t testing.TB, src string) *ObjectSynNode {
+	t.Helper()
+	syn, err := ParseSynString(src)
+	if err != nil {
+		t.Fatalf("failed to parse syntax of %q: %v", src, err)
+	}
+	return syn
+}
+
+// parseTestSem parses the source with testSemantics.
+func parseTestSem(t testing.TB, src string, opts ...SemOption) (SemNode, error) {
+	t.Helper()
+	return ParseSem(mustParseSyn(t, src), testSemantics(), opts...)
+}
+
+// mustParseTestSem is like parseTestSem, but fails the test if there is an error.
+func mustParseTestSem(t testing.TB, src string, opts ...SemOption) SemNode {
+	t.Helper()
+	sem, err := parseTestSem(t, src, opts...)
+	if err != nil {
+		t.Fatalf("failed to parse semantics of %q: %v", src, err)
+	}
+	return sem
+}
+
+// textOf returns all of the text in a semantic tree, in document order.
+func textOf(n SemNode) string {
+	res := ""
+	Walk(n, func(n, _ SemNode) bool {
+		if t, ok := n.(*TextSemNode); ok {
+			res += t.Text
+		}
+		return true
+	})
+	return res
+}
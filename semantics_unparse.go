@@ -0,0 +1,192 @@
+package obtext
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// UnparsableSemNode is an optional interface for SemNodes that can be converted back into syntax, which is required by UnparseSem.
+// All of the base nodes in this package implement it.
+type UnparsableSemNode interface {
+	SemNode
+	// Unparse is the inverse of ParseArgs, returning args that would parse into an equal node.
+	Unparse() ([]*ContentBlockSemNode, error)
+}
+
+// UnparseSem converts a semantic tree back into a syntax tree, which can then be written as obtext with FormatSynSource.
+// Every node in the tree, apart from TextSemNodes, ContentBlockSemNodes and UnknownSemNodes, must implement UnparsableSemNode.
+// The root must not be a TextSemNode or ContentBlockSemNode, as a document is always an object.
+// Each object is named by its SyntaxType, so nodes that were registered under another name, such as in a pack, should be unparsed with Registry.UnparseSem.
+func UnparseSem(n SemNode) (*ObjectSynNode, error) {
+	return unparser{}.root(n)
+}
+
+// UnparseSem is like the package level UnparseSem, but names each object by the name that its type is registered under.
+// If a type is registered under more than one name, its SyntaxType is used if that is one of them, otherwise the first name in sorted order is used.
+func (r *Registry) UnparseSem(n SemNode) (*ObjectSynNode, error) {
+	return r.unparser().root(n)
+}
+
+// unparser converts semantic nodes back into syntax.
+type unparser struct {
+	// names are the names to use for objects of each type, which are otherwise named by their SyntaxType.
+	names map[reflect.Type]string
+}
+
+// unparser returns an unparser that names objects as they are registered.
+func (r *Registry) unparser() unparser {
+	names := make(map[reflect.Type]string)
+	for _, name := range r.Names() {
+		e, ok := r.nodes[name]
+		if !ok {
+			// Aliases are never used for unparsing
+			continue
+		}
+		t := reflect.TypeOf(e.proto)
+		if _, ok := names[t]; !ok || name == e.proto.SyntaxType() {
+			names[t] = name
+		}
+	}
+	return unparser{names: names}
+}
+
+func (u unparser) root(n SemNode) (*ObjectSynNode, error) {
+	el, err := u.element(n)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := el.(*ObjectSynNode)
+	if !ok {
+		return nil, fmt.Errorf("cannot unparse %T as the root of a document", n)
+	}
+	return obj, nil
+}
+
+func (u unparser) element(n SemNode) (SynElement, error) {
+	var args []*ContentBlockSemNode
+	switch n := n.(type) {
+	case *TextSemNode:
+		return &TextSynNode{Value: n.Text}, nil
+	case *ContentBlockSemNode:
+		return nil, fmt.Errorf("cannot unparse a content block as an element")
+	case UnparsableSemNode:
+		var err error
+		args, err = n.Unparse()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cannot unparse %T, as it does not implement UnparsableSemNode", n)
+	}
+	obj := &ObjectSynNode{Type: u.name(n), Args: make([]*ArgSynNode, len(args))}
+	for i, a := range args {
+		arg, err := u.arg(a)
+		if err != nil {
+			return nil, err
+		}
+		obj.Args[i] = arg
+	}
+	return obj, nil
+}
+
+func (u unparser) arg(c *ContentBlockSemNode) (*ArgSynNode, error) {
+	arg := &ArgSynNode{Elements: make([]SynElement, len(c.Elements)), CastValue: c.CastValue}
+	for i, e := range c.Elements {
+		el, err := u.element(e)
+		if err != nil {
+			return nil, err
+		}
+		arg.Elements[i] = el
+	}
+	return arg, nil
+}
+
+// name returns the name of the object that the node is written as.
+func (u unparser) name(n SemNode) string {
+	if _, ok := n.(*UnknownSemNode); ok {
+		return n.SyntaxType()
+	}
+	if name, ok := u.names[reflect.TypeOf(n)]; ok {
+		return name
+	}
+	return n.SyntaxType()
+}
+
+// textBlock returns a content block containing only the given text.
+func textBlock(text string) *ContentBlockSemNode {
+	return &ContentBlockSemNode{Elements: []SemNode{&TextSemNode{Text: text}}}
+}
+
+// TaggedUnparse returns the args that would parse into node, which must be a pointer to a struct with `obt` tags.
+// It can be used to implement the Unparse method of an UnparsableSemNode alongside ParseTaggedArgs.
+// Trailing optional arguments are left out if they have their default value.
+func TaggedUnparse(node any) ([]*ContentBlockSemNode, error) {
+	v := reflect.ValueOf(node)
+	s := schemaFor(v.Type())
+	v = v.Elem()
+	args := make([]*ContentBlockSemNode, 0, len(s.fields))
+	// required is the number of args that must be kept, as they are required or not default
+	required := 0
+	for _, f := range s.fields {
+		field := v.FieldByIndex(f.index)
+		if f.variadic {
+			for i := 0; i < field.Len(); i++ {
+				arg, err := unparseField(&f, field.Index(i))
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+			}
+			required = len(args)
+			continue
+		}
+		if f.kind.cast == nil && field.IsNil() {
+			// Missing optional content is written as empty content, but may be left out if it is at the end
+			args = append(args, &ContentBlockSemNode{Elements: []SemNode{}})
+			continue
+		}
+		arg, err := unparseField(&f, field)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if f.required || f.kind.cast == nil || !reflect.DeepEqual(field.Interface(), f.def().Interface()) {
+			required = len(args)
+		}
+	}
+	return args[:required], nil
+}
+
+// unparseField converts the value of a field back into an arg.
+func unparseField(f *schemaField, v reflect.Value) (*ContentBlockSemNode, error) {
+	if f.kind.cast == nil {
+		return v.Interface().(*ContentBlockSemNode), nil
+	}
+	switch val := v.Interface().(type) {
+	case *url.URL:
+		return textBlock(val.String()), nil
+	case url.URL:
+		return textBlock(val.String()), nil
+	case time.Time:
+		return textBlock(val.Format(f.format)), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return textBlock(v.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return textBlock(strconv.FormatInt(v.Int(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return textBlock(strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())), nil
+	case reflect.Bool:
+		return textBlock(strconv.FormatBool(v.Bool())), nil
+	}
+	return nil, fmt.Errorf("cannot unparse field %s of type %s", f.name, v.Type())
+}
+
+// Unparse implements the UnparsableSemNode interface.
+func (u *UnknownSemNode) Unparse() ([]*ContentBlockSemNode, error) {
+	return u.Args, nil
+}
@@ -0,0 +1,118 @@
+package obtext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnparseSemRoundTrip(t *testing.T) {
+	srcs := []string{
+		"@doc{Some @bold{bold} text with @link{https://example.com}{a link} and @code{go}{x := 1}.}",
+		"@doc{@list{a}{b @bold{c}}{} @figure{caption}{x}{y}}",
+		`@doc{escaped \@ \{ \} \\ text}`,
+	}
+	for _, src := range srcs {
+		syn := mustParseSyn(t, src)
+		sem := mustParseTestSem(t, src)
+		unparsed, err := UnparseSem(sem)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		reparsed := mustParseSyn(t, FormatSynSource(unparsed))
+		if !reflect.DeepEqual(withoutPositions(syn), withoutPositions(reparsed)) {
+			t.Errorf("%s: unparsed as %s", src, FormatSynSource(unparsed))
+		}
+	}
+}
+
+func TestUnparseSemTypedArgs(t *testing.T) {
+	src := "@post{7}{1.5}{true}{https://a.b/c}{2020-01-02}{live}{a/b}{my-slug}{ann}{bob}"
+	sem, err := ParseSem(mustParseSyn(t, src), []SemNode{&testPost{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unparsed, err := UnparseSem(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatSynSource(unparsed); got != src {
+		t.Errorf("got %s, want %s", got, src)
+	}
+}
+
+func TestRegistryUnparseSemNames(t *testing.T) {
+	reg := mustNewRegistry(t, &testDoc{}, &testBold{})
+	if err := reg.RegisterPack("gallery", &testGrid{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.RegisterAs("em", &testPara{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Alias("b", "bold"); err != nil {
+		t.Fatal(err)
+	}
+	src := "@doc{@gallery.grid{x} @em{y} @b{z}}"
+	sem, err := reg.Parse(mustParseSyn(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unparsed, err := reg.UnparseSem(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Aliases are written as the name that they refer to
+	if got := FormatSynSource(unparsed); got != "@doc{@gallery.grid{x}@em{y}@bold{z}}" {
+		t.Errorf("got %s", got)
+	}
+	// Without the registry, only the SyntaxType is known
+	unparsed, err = UnparseSem(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatSynSource(unparsed); got != "@doc{@grid{x}@para{y}@bold{z}}" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestRegistryUnparseSemPrefersSyntaxType(t *testing.T) {
+	reg := mustNewRegistry(t, &testBold{})
+	if err := reg.RegisterAs("a", &testBold{}); err != nil {
+		t.Fatal(err)
+	}
+	unparsed, err := reg.UnparseSem(mustParseTestSem(t, "@bold{x}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unparsed.Type != "bold" {
+		t.Errorf("got @%s, want the SyntaxType of a type that is registered twice", unparsed.Type)
+	}
+}
+
+func TestUnparseSemUnknown(t *testing.T) {
+	sem := mustParseTestSem(t, "@doc{@foo.bar{a}{b}}", WithUnknownObjects(UnknownKeep))
+	unparsed, err := UnparseSem(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatSynSource(unparsed); got != "@doc{@foo.bar{a}{b}}" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestUnparseSemErrors(t *testing.T) {
+	frozen, err := ParseSem(mustParseSyn(t, "@frozen{a}"), []SemNode{&testFrozen{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := []SemNode{
+		&ContentBlockSemNode{},
+		&TextSemNode{Text: "x"},
+		frozen,
+	}
+	for _, root := range roots {
+		if _, err := UnparseSem(root); err == nil {
+			t.Errorf("expected an error unparsing %T", root)
+		}
+	}
+}
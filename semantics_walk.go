@@ -0,0 +1,116 @@
+package obtext
+
+import "fmt"
+
+// MutableSemNode is an optional interface for SemNodes whose children can be replaced, which is required by Transform.
+// All of the base nodes in this package implement it.
+type MutableSemNode interface {
+	SemNode
+	// SetChild replaces the i-th node returned by Children with n.
+	// It returns an error if i is out of range or n cannot be used as that child.
+	SetChild(i int, n SemNode) error
+}
+
+// Walk calls f on every node in the tree in document order, along with its parent (which is nil for the root).
+// If f returns false, the children of that node are skipped.
+func Walk(root SemNode, f func(n, parent SemNode) bool) {
+	walk(root, nil, f)
+}
+
+func walk(n, parent SemNode, f func(n, parent SemNode) bool) {
+	if !f(n, parent) {
+		return
+	}
+	for _, c := range n.Children() {
+		walk(c, n, f)
+	}
+}
+
+// FindAll returns every node in the tree of type T, in document order.
+// For example, FindAll[*markup.ImageSemNode](root) returns all images.
+func FindAll[T SemNode](root SemNode) []T {
+	res := make([]T, 0)
+	Walk(root, func(n, _ SemNode) bool {
+		if t, ok := n.(T); ok {
+			res = append(res, t)
+		}
+		return true
+	})
+	return res
+}
+
+// Transform calls f on every node in the tree, replacing each node with the one that f returns, and returns the new root.
+// Children are transformed before their parent, so f can wrap a node without it being transformed again.
+// Inside a ContentBlockSemNode, f may return nil to remove the node. Anywhere else, the parent must be a MutableSemNode if a child is replaced.
+func Transform(root SemNode, f func(n SemNode) (SemNode, error)) (SemNode, error) {
+	if block, ok := root.(*ContentBlockSemNode); ok {
+		// Content blocks are handled directly so that nodes can be removed
+		elements := make([]SemNode, 0, len(block.Elements))
+		for _, e := range block.Elements {
+			te, err := Transform(e, f)
+			if err != nil {
+				return nil, err
+			}
+			if te != nil {
+				elements = append(elements, te)
+			}
+		}
+		block.Elements = elements
+	} else {
+		for i, c := range root.Children() {
+			tc, err := Transform(c, f)
+			if err != nil {
+				return nil, err
+			}
+			if tc == c {
+				continue
+			}
+			m, ok := root.(MutableSemNode)
+			if !ok {
+				return nil, fmt.Errorf("cannot replace child %d of %T, as it does not implement MutableSemNode", i, root)
+			}
+			if err := m.SetChild(i, tc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f(root)
+}
+
+// ReplaceAll replaces every node of type T in the tree with the result of f, and returns the new root.
+// For example, it can wrap every table in a scroll container. See Transform for details.
+func ReplaceAll[T SemNode](root SemNode, f func(n T) SemNode) (SemNode, error) {
+	return Transform(root, func(n SemNode) (SemNode, error) {
+		if t, ok := n.(T); ok {
+			return f(t), nil
+		}
+		return n, nil
+	})
+}
+
+// SetChild implements the MutableSemNode interface.
+func (c *ContentBlockSemNode) SetChild(i int, n SemNode) error {
+	if i < 0 || i >= len(c.Elements) {
+		return fmt.Errorf("child index %d out of range", i)
+	}
+	c.Elements[i] = n
+	return nil
+}
+
+// SetChild implements the MutableSemNode interface.
+func (u *UnknownSemNode) SetChild(i int, n SemNode) error {
+	return setContentChild(u.Args, i, n)
+}
+
+// setContentChild replaces the i-th content block in blocks with n, which must also be a content block.
+func setContentChild(blocks []*ContentBlockSemNode, i int, n SemNode) error {
+	if i < 0 || i >= len(blocks) {
+		return fmt.Errorf("child index %d out of range", i)
+	}
+	block, ok := n.(*ContentBlockSemNode)
+	if !ok {
+		return fmt.Errorf("child %d must be a content block, got %T", i, n)
+	}
+	blocks[i] = block
+	return nil
+}
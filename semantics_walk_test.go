@@ -0,0 +1,157 @@
+package obtext
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testFrozen has children, but they cannot be replaced.
+type testFrozen struct {
+	Content *ContentBlockSemNode
+}
+
+func (*testFrozen) SyntaxType() string { return "frozen" }
+
+func (f *testFrozen) ParseArgs(args []*ContentBlockSemNode) error {
+	if err := checkArgCount(args, 1, 1); err != nil {
+		return err
+	}
+	f.Content = args[0]
+	return nil
+}
+
+func (f *testFrozen) Children() []SemNode { return []SemNode{f.Content} }
+
+func TestWalk(t *testing.T) {
+	sem := mustParseTestSem(t, "@doc{a @bold{b} @para{c @bold{d}}}")
+	var types []string
+	Walk(sem, func(n, parent SemNode) bool {
+		if (parent == nil) != (n == sem) {
+			t.Errorf("wrong parent %T for %T", parent, n)
+		}
+		switch n := n.(type) {
+		case *TextSemNode:
+			types = append(types, n.Text)
+		case *ContentBlockSemNode:
+		default:
+			types = append(types, "@"+n.SyntaxType())
+		}
+		// Skip the contents of paragraphs
+		return !isType[*testPara](n)
+	})
+	expected := []string{"@doc", "a ", "@bold", "b", "@para"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("got %v, want %v", types, expected)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	sem := mustParseTestSem(t, "@doc{@bold{a} @para{@bold{b} @bold{c}}}")
+	var texts []string
+	for _, b := range FindAll[*testBold](sem) {
+		texts = append(texts, textOf(b))
+	}
+	if !reflect.DeepEqual(texts, []string{"a", "b", "c"}) {
+		t.Errorf("got %v", texts)
+	}
+	if links := FindAll[*testLink](sem); len(links) != 0 {
+		t.Errorf("found %d links in a document without any", len(links))
+	}
+}
+
+func TestTransform(t *testing.T) {
+	sem := mustParseTestSem(t, "@doc{a @bold{b} @para{c @bold{d}}}")
+	// Remove all bold text, then wrap the document in a content block
+	res, err := Transform(sem, func(n SemNode) (SemNode, error) {
+		switch n.(type) {
+		case *testBold:
+			return nil, nil
+		case *testDoc:
+			return &ContentBlockSemNode{Elements: []SemNode{n}}, nil
+		}
+		return n, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isType[*ContentBlockSemNode](res) {
+		t.Fatalf("got root %T, want a content block", res)
+	}
+	if got := textOf(res); got != "a c " {
+		t.Errorf("got text %q after removing bold", got)
+	}
+}
+
+func TestReplaceAll(t *testing.T) {
+	sem := mustParseTestSem(t, "@doc{@bold{a} @list{@bold{b}}{c}}")
+	// Wrap every bold in a paragraph, which must not be transformed again
+	res, err := ReplaceAll(sem, func(b *testBold) SemNode {
+		p := &testPara{}
+		p.Content = &ContentBlockSemNode{Elements: []SemNode{b}}
+		return p
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paras := FindAll[*testPara](res); len(paras) != 2 {
+		t.Errorf("got %d paragraphs, want 2", len(paras))
+	}
+	if bolds := FindAll[*testBold](res); len(bolds) != 2 {
+		t.Errorf("got %d bolds, want 2", len(bolds))
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	sem, err := ParseSem(mustParseSyn(t, "@frozen{a}"), []SemNode{&testFrozen{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replacing the child of a node that is not a MutableSemNode fails
+	_, err = Transform(sem, func(n SemNode) (SemNode, error) {
+		if isType[*ContentBlockSemNode](n) {
+			return &ContentBlockSemNode{}, nil
+		}
+		return n, nil
+	})
+	if err == nil {
+		t.Errorf("expected an error replacing a child of a node that is not mutable")
+	}
+	// Args can only be replaced with content blocks
+	_, err = Transform(mustParseTestSem(t, "@doc{a}"), func(n SemNode) (SemNode, error) {
+		if isType[*ContentBlockSemNode](n) {
+			return &TextSemNode{Text: "x"}, nil
+		}
+		return n, nil
+	})
+	if err == nil {
+		t.Errorf("expected an error replacing an arg with text")
+	}
+	// Errors from f are returned
+	failed := errors.New("failed")
+	_, err = Transform(mustParseTestSem(t, "@doc{a}"), func(n SemNode) (SemNode, error) { return nil, failed })
+	if !errors.Is(err, failed) {
+		t.Errorf("got %v, want the error from f", err)
+	}
+}
+
+func TestTaggedSetChild(t *testing.T) {
+	list := mustParseTestSem(t, "@list{a}{b}{c}").(*testList)
+	replacement := &ContentBlockSemNode{Elements: []SemNode{&TextSemNode{Text: "x"}}}
+	if err := list.SetChild(1, replacement); err != nil {
+		t.Fatal(err)
+	}
+	if got := textOf(list); got != "axc" {
+		t.Errorf("got %q after replacing the second item", got)
+	}
+	if err := list.SetChild(3, replacement); err == nil {
+		t.Errorf("expected an error for an index out of range")
+	}
+	unknown := &UnknownSemNode{Type: "foo", Args: []*ContentBlockSemNode{{}}}
+	if err := unknown.SetChild(0, replacement); err != nil || unknown.Args[0] != replacement {
+		t.Errorf("failed to replace the arg of an unknown node: %v", err)
+	}
+	if err := unknown.SetChild(0, &TextSemNode{}); err == nil {
+		t.Errorf("expected an error replacing an arg with text")
+	}
+}
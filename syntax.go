@@ -1,5 +1,7 @@
 package obtext
 
+import "fmt"
+
 // SynElement is either an Object or Text.
 type SynElement interface {
 	isSynElement()
@@ -8,10 +10,35 @@ type SynElement interface {
 func (ObjectSynNode) isSynElement() {}
 func (TextSynNode) isSynElement()   {}
 
+// Position is a location in the obtext source that a syntax node was parsed from.
+type Position struct {
+	// Offset is the byte offset from the start of the source, starting at 0.
+	Offset int
+	// Line is the line number, starting at 1.
+	Line int
+	// Column is the byte offset from the start of the line, starting at 1.
+	Column int
+}
+
+// IsValid returns true if the position was set by the parser.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in the form 'line:column'.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
 // ObjectSynNode is a syntax node representing an object: @object_name{arg1}{arg2}...
 type ObjectSynNode struct {
 	Type string
 	Args []*ArgSynNode
+	// Pos is the position of the '@' that starts this object.
+	Pos Position
 }
 
 // ArgSynNode is a syntax node representing a list of elements.
@@ -19,10 +46,19 @@ type ObjectSynNode struct {
 type ArgSynNode struct {
 	Elements []SynElement
 	// This may be nil, however during validation it is possible that this will get populated according to the validation constraints.
+	// ParseSem populates it for arguments of nodes that declare their argument types with `obt` tags (see ParseTaggedArgs),
+	// replacing any value from an earlier parse. The same value is stored in the ContentBlockSemNode of the arg, which is what the node reads,
+	// so the same syntax tree can be parsed with different semantics. UnparseSem copies it back from there.
 	CastValue any
+	// Pos is the position of the '{' that starts this arg.
+	Pos Position
+	// End is the position just after the '}' that ends this arg.
+	End Position
 }
 
 // TextSynNode is a syntax node representing a text value.
 type TextSynNode struct {
 	Value string
+	// Pos is the position of the start of this text, before any whitespace was trimmed.
+	Pos Position
 }
@@ -1,6 +1,10 @@
 package obtext
 
-import "github.com/fatih/color"
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
 
 // FormatSyn returns a string representation of the syntax tree object with nice indentation.
 func FormatSyn(o *ObjectSynNode) string {
@@ -35,3 +39,59 @@ func formatSyn(o *ObjectSynNode, indent string, withAnsiiColors bool) string {
 	}
 	return out
 }
+
+// sourceLineWidth is the width above which FormatSynSource will try to split an arg over multiple lines.
+const sourceLineWidth = 80
+
+// FormatSynSource returns obtext source that parses into the given syntax tree, escaping any special characters in text.
+// Args that only contain objects are split over multiple lines with tab indentation, and all other args are written on one line,
+// as adding whitespace to them would change the text.
+// Any tree returned by ParseSynBytes can be formatted and parsed again without changing it. Some other trees cannot be written as source:
+// text that ends with a backslash directly before an object, text that starts or ends with whitespace at the start or end of an arg,
+// and an object without args that is directly followed by text that would continue its name, such as "@br" followed by "2nd".
+func FormatSynSource(o *ObjectSynNode) string {
+	return formatSynSource(o, "")
+}
+
+func formatSynSource(o *ObjectSynNode, indent string) string {
+	out := "@" + o.Type
+	for _, a := range o.Args {
+		out += "{" + formatSynSourceArg(a, indent) + "}"
+	}
+	return out
+}
+
+func formatSynSourceArg(a *ArgSynNode, indent string) string {
+	inline := ""
+	onlyObjects := true
+	for i, e := range a.Elements {
+		switch e := e.(type) {
+		case *ObjectSynNode:
+			inline += formatSynSource(e, indent)
+		case *TextSynNode:
+			inline += escapeSynText(e.Value)
+			if i == len(a.Elements)-1 && strings.HasSuffix(e.Value, "\\") {
+				// A backslash before the closing brace would escape it, but whitespace at the end of an arg is removed when parsing
+				inline += " "
+			}
+			onlyObjects = false
+		}
+	}
+	if !onlyObjects || len(a.Elements) == 0 || (len(inline) <= sourceLineWidth && !strings.Contains(inline, "\n")) {
+		return inline
+	}
+	// The whitespace between objects is removed when parsing, so they can each go on their own line
+	out := "\n"
+	for _, e := range a.Elements {
+		out += indent + "\t" + formatSynSource(e.(*ObjectSynNode), indent+"\t") + "\n"
+	}
+	return out + indent
+}
+
+// escapeSynText escapes the special characters in text so that it is not parsed as objects or args.
+// Backslashes are left as they are, as only a backslash before a special character is an escape.
+func escapeSynText(s string) string {
+	s = strings.ReplaceAll(s, "@", "\\@")
+	s = strings.ReplaceAll(s, "{", "\\{")
+	return strings.ReplaceAll(s, "}", "\\}")
+}
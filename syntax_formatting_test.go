@@ -0,0 +1,123 @@
+package obtext
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withoutPositions returns a copy of the syntax tree with all positions removed, so that trees parsed from differently formatted source can be compared.
+func withoutPositions(o *ObjectSynNode) *ObjectSynNode {
+	res := &ObjectSynNode{Type: o.Type, Args: make([]*ArgSynNode, len(o.Args))}
+	for i, a := range o.Args {
+		arg := &ArgSynNode{Elements: make([]SynElement, len(a.Elements)), CastValue: a.CastValue}
+		for j, e := range a.Elements {
+			switch e := e.(type) {
+			case *ObjectSynNode:
+				arg.Elements[j] = withoutPositions(e)
+			case *TextSynNode:
+				arg.Elements[j] = &TextSynNode{Value: e.Value}
+			}
+		}
+		res.Args[i] = arg
+	}
+	return res
+}
+
+// assertRoundTrip checks that formatting the syntax tree of src parses back into the same tree.
+func assertRoundTrip(t *testing.T, src string) {
+	t.Helper()
+	syn, err := ParseSynString(src)
+	if err != nil {
+		return
+	}
+	formatted := FormatSynSource(syn)
+	reparsed, err := ParseSynString(formatted)
+	if err != nil {
+		t.Errorf("%q formatted as %q, which does not parse: %v", src, formatted, err)
+		return
+	}
+	if !reflect.DeepEqual(withoutPositions(syn), withoutPositions(reparsed)) {
+		t.Errorf("%q formatted as %q, which parses differently:\n%s\n%s", src, formatted, FormatSyn(syn), FormatSyn(reparsed))
+	}
+}
+
+func TestParseEscapes(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected string
+	}{
+		{`@a{\@ \{ \}}`, `@ { }`},
+		// A backslash is only an escape before a special character
+		{`@a{C:\ }`, `C:\`},
+		{`@a{C:\dir\file}`, `C:\dir\file`},
+		{`@a{\\@}`, `\@`},
+		{`@a{x\\ }`, `x\\`},
+	}
+	for _, c := range cases {
+		syn := mustParseSyn(t, c.src)
+		if got := syn.Args[0].Elements[0].(*TextSynNode).Value; got != c.expected {
+			t.Errorf("%s: got %q, want %q", c.src, got, c.expected)
+		}
+	}
+}
+
+func TestFormatSynSourceRoundTrip(t *testing.T) {
+	srcs := []string{
+		`@icode{C:\ }`,
+		`@a{C:\\ }`,
+		`@a{\\\@b}`,
+		`@a{x\ @b{y}}`,
+		`@a{\@ \{ \} \\ \x}`,
+		`@a{@br 2nd line @br .dot @br{}text}`,
+		`@a{@br and @br}`,
+		`@a{@b{x}y@c}`,
+		`@a{@b \{x\}}`,
+		"@doc{@p{Some @b{long} text that goes on for a while, so the arg is long enough to be split} @p{more text} @hr}",
+		"@doc{@list{@item{a}@item{b}}{@x}}",
+	}
+	for _, src := range srcs {
+		assertRoundTrip(t, src)
+	}
+}
+
+func TestFormatSynSourceEscapes(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected string
+	}{
+		{`@a{\@b \{c\}}`, `@a{\@b \{c\}}`},
+		{`@a{\\@b}`, `@a{\\@b}`},
+		// The space stops the backslash at the end of the arg from escaping the closing brace
+		{`@icode{C:\ }`, `@icode{C:\ }`},
+		{`@icode{C:\ @b}`, `@icode{C:\ @b}`},
+	}
+	for _, c := range cases {
+		if got := FormatSynSource(mustParseSyn(t, c.src)); got != c.expected {
+			t.Errorf("%s: got %q, want %q", c.src, got, c.expected)
+		}
+	}
+}
+
+func TestFormatSynSourceRandomRoundTrip(t *testing.T) {
+	// Random documents are mostly invalid, but enough parse to find any text that does not survive formatting
+	pieces := []string{"@a", "@b.c", "{", "}", `\`, `\@`, `\{`, `\}`, `\\`, " ", "\n", "x", "1", ".", "_", "@"}
+	rng := rand.New(rand.NewSource(1))
+	parsed := 0
+	for i := 0; i < 20000; i++ {
+		sb := &strings.Builder{}
+		sb.WriteString("@doc{")
+		for j := rng.Intn(12); j >= 0; j-- {
+			sb.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		sb.WriteString("}")
+		if _, err := ParseSynString(sb.String()); err == nil {
+			parsed++
+		}
+		assertRoundTrip(t, sb.String())
+	}
+	if parsed < 1000 {
+		t.Errorf("only %d random documents parsed, so the test is not checking much", parsed)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -12,7 +13,8 @@ var textRegexp = regexp.MustCompile(`(?:\\[@}]|[^@}])+`)
 var argRegexpStart = regexp.MustCompile("[ \n\r\t]*{")
 var argRegexpEnd = regexp.MustCompile("}")
 
-var objRegexpName = regexp.MustCompile("@([a-zA-Z0-9_]+)")
+// Object names may be namespaced with dots, such as @gallery.grid
+var objRegexpName = regexp.MustCompile(`@([a-zA-Z0-9_]+(?:\.[a-zA-Z0-9_]+)*)`)
 
 // ParseSynString is a convenience function that calls ParseBytes after converting the string to a byte slice
 func ParseSynString(data string) (*ObjectSynNode, error) {
@@ -28,19 +30,40 @@ func ParseSynReader(data io.Reader) (*ObjectSynNode, error) {
 	return ParseSynBytes(buf)
 }
 
+// SynError is returned by ParseSynBytes when the source is not valid obtext.
+type SynError struct {
+	// Pos is the position of the problem, which for an unclosed arg is the position of its '{'.
+	Pos Position
+	// Msg describes the problem.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *SynError) Error() string {
+	return fmt.Sprintf("%s: failed to parse: %s", e.Pos, e.Msg)
+}
+
 // ParseSynBytes parses the given byte slice and returns the AST, or an error if the data is invalid.
+// Any returned error is a *SynError, which contains the position of the furthest point that the parser could not get past.
 // The resulting AST represents only they syntax, and should probably not be used directly.
 // Instead, you should call ParseSem on the result to parse the syntax tree into a semantics tree.
 func ParseSynBytes(data []byte) (*ObjectSynNode, error) {
+	p := newSynParser(data)
 	// Initially, trim all whitespace from front and end (some editors add a newline at the end)
 	data = []byte(strings.Trim(string(data), " \r\n\t"))
 	// First, try to parse the messy ast
-	obj, remaining := tryParseObject(data)
+	obj, remaining := p.tryParseObject(data)
 	if obj == nil {
-		return nil, fmt.Errorf("failed to parse: invalid syntax")
+		p.failAt(data, &SynError{Pos: p.pos(data), Msg: "the document must start with an object"})
+		return nil, p.failure
 	}
 	if len(remaining) > 0 {
-		return nil, fmt.Errorf("failed to parse: remaining characters detected")
+		if remaining[0] == '}' {
+			p.failAt(remaining, &SynError{Pos: p.pos(remaining), Msg: "unexpected '}', which should be escaped as \\}"})
+		} else {
+			p.failAt(remaining, &SynError{Pos: p.pos(remaining), Msg: "unexpected text after the end of the document, which must be a single object"})
+		}
+		return nil, p.failure
 	}
 	// Now traverse the tree, removing all text that is only whitespace
 	removeWhitespaceOnlyTextFromChildren(obj)
@@ -132,20 +155,73 @@ func consume(reg *regexp.Regexp, data []byte) (bool, []string, []byte) {
 	return true, groups, data[locs[1]:]
 }
 
-func tryParseText(data []byte) (*TextSynNode, []byte) {
+// synParser keeps track of the source being parsed, so that the position of each node can be recorded.
+type synParser struct {
+	// src is the full source, before any trimming.
+	src []byte
+	// end is the offset in src of the end of the trimmed data that is parsed.
+	end int
+	// lineStarts is the offset of the first byte of each line in src.
+	lineStarts []int
+	// failure is the error at the furthest point that could not be parsed, which is reported if the whole document fails.
+	failure       *SynError
+	failureOffset int
+}
+
+// failAt records an error at the start of data, unless there is already one at or after it.
+// When several nested args fail at the same point, the innermost one is kept as it is the most specific.
+func (p *synParser) failAt(data []byte, err *SynError) {
+	offset := p.end - len(data)
+	if p.failure == nil || offset > p.failureOffset {
+		p.failure = err
+		p.failureOffset = offset
+	}
+}
+
+func newSynParser(src []byte) *synParser {
+	lineStarts := []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &synParser{
+		src:        src,
+		end:        len(strings.TrimRight(string(src), " \r\n\t")),
+		lineStarts: lineStarts,
+	}
+}
+
+// pos returns the position of the start of data, which must be a suffix of the trimmed source.
+func (p *synParser) pos(data []byte) Position {
+	return p.posAt(p.end - len(data))
+}
+
+// posAt returns the position of the given offset in the source.
+func (p *synParser) posAt(offset int) Position {
+	line := sort.Search(len(p.lineStarts), func(i int) bool { return p.lineStarts[i] > offset })
+	return Position{
+		Offset: offset,
+		Line:   line,
+		Column: offset - p.lineStarts[line-1] + 1,
+	}
+}
+
+func (p *synParser) tryParseText(data []byte) (*TextSynNode, []byte) {
 	parsed, groups, remaining := consume(textRegexp, data)
 	if !parsed {
 		return nil, nil
 	}
-	return &TextSynNode{groups[0]}, remaining
+	return &TextSynNode{Value: groups[0], Pos: p.pos(data)}, remaining
 }
 
-func tryParseArg(data []byte) (*ArgSynNode, []byte) {
+func (p *synParser) tryParseArg(data []byte) (*ArgSynNode, []byte) {
 	// Parse some whitespace then a open bracket
-	parsed, _, remaining := consume(argRegexpStart, data)
+	parsed, groups, remaining := consume(argRegexpStart, data)
 	if !parsed {
 		return nil, nil
 	}
+	pos := p.pos(data[len(groups[0])-1:])
 	data = remaining
 	elements := make([]SynElement, 0)
 	for {
@@ -153,11 +229,11 @@ func tryParseArg(data []byte) (*ArgSynNode, []byte) {
 		parsed, _, remaining = consume(argRegexpEnd, data)
 		if parsed {
 			// sucsess! return the object arg
-			oa := &ArgSynNode{Elements: elements}
+			oa := &ArgSynNode{Elements: elements, Pos: pos, End: p.pos(remaining)}
 			return oa, remaining
 		}
 		// Now try to parse a new object
-		obj, remaining := tryParseObject(data)
+		obj, remaining := p.tryParseObject(data)
 		if obj != nil {
 			data = remaining
 			elements = append(elements, obj)
@@ -165,7 +241,7 @@ func tryParseArg(data []byte) (*ArgSynNode, []byte) {
 		}
 
 		// Finally try to parse some text
-		txt, remaining := tryParseText(data)
+		txt, remaining := p.tryParseText(data)
 		if txt != nil {
 			data = remaining
 			elements = append(elements, txt)
@@ -173,22 +249,28 @@ func tryParseArg(data []byte) (*ArgSynNode, []byte) {
 		}
 
 		// If all of those failed, this is not parseable, so return nil
+		if len(data) == 0 {
+			p.failAt(data, &SynError{Pos: pos, Msg: "'{' is never closed"})
+		} else {
+			p.failAt(data, &SynError{Pos: p.pos(data), Msg: "'@' must be followed by an object name, or escaped as \\@"})
+		}
 		return nil, nil
 	}
 }
 
-func tryParseObject(data []byte) (*ObjectSynNode, []byte) {
+func (p *synParser) tryParseObject(data []byte) (*ObjectSynNode, []byte) {
 	// Try to parse @obj_type
 	parsed, groups, remaining := consume(objRegexpName, data)
 	if !parsed {
 		return nil, nil
 	}
 	objType := groups[1]
+	pos := p.pos(data)
 	data = remaining
 	args := make([]*ArgSynNode, 0)
 	// Parse all remaining args. An arg can be preceded by whitespace (this is dealt with in the arg parser)
 	for {
-		arg, remaining := tryParseArg(data)
+		arg, remaining := p.tryParseArg(data)
 		if arg == nil {
 			break
 		}
@@ -198,5 +280,6 @@ func tryParseObject(data []byte) (*ObjectSynNode, []byte) {
 	return &ObjectSynNode{
 		Type: objType,
 		Args: args,
+		Pos:  pos,
 	}, data
 }
@@ -0,0 +1,48 @@
+package obtext
+
+import "testing"
+
+func TestParseSynPositions(t *testing.T) {
+	src := "@doc{\n  @para{hi @bold{x}}\n}"
+	doc := mustParseSyn(t, src)
+	para := doc.Args[0].Elements[0].(*ObjectSynNode)
+	text := para.Args[0].Elements[0].(*TextSynNode)
+	bold := para.Args[0].Elements[1].(*ObjectSynNode)
+	cases := []struct {
+		name string
+		got  Position
+		want Position
+	}{
+		{"doc", doc.Pos, Position{Offset: 0, Line: 1, Column: 1}},
+		{"doc arg", doc.Args[0].Pos, Position{Offset: 4, Line: 1, Column: 5}},
+		{"doc arg end", doc.Args[0].End, Position{Offset: len(src), Line: 3, Column: 2}},
+		{"para", para.Pos, Position{Offset: 8, Line: 2, Column: 3}},
+		{"text", text.Pos, Position{Offset: 14, Line: 2, Column: 9}},
+		{"bold", bold.Pos, Position{Offset: 17, Line: 2, Column: 12}},
+		{"bold arg end", bold.Args[0].End, Position{Offset: 25, Line: 2, Column: 20}},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s: got position %+v, want %+v", c.name, c.got, c.want)
+		}
+	}
+	if src[bold.Pos.Offset] != '@' || src[bold.Args[0].End.Offset-1] != '}' {
+		t.Errorf("offsets do not point at the object and end of its arg")
+	}
+}
+
+func TestParseSynPositionsWithLeadingWhitespace(t *testing.T) {
+	doc := mustParseSyn(t, "\n\n  @doc{x}\n")
+	if want := (Position{Offset: 4, Line: 3, Column: 3}); doc.Pos != want {
+		t.Errorf("got position %+v, want %+v", doc.Pos, want)
+	}
+}
+
+func TestPositionString(t *testing.T) {
+	if s := (Position{Offset: 10, Line: 2, Column: 5}).String(); s != "2:5" {
+		t.Errorf("got %q, want 2:5", s)
+	}
+	if s := (Position{}).String(); s != "-" {
+		t.Errorf("got %q for an invalid position, want -", s)
+	}
+}
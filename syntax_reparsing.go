@@ -0,0 +1,161 @@
+package obtext
+
+// SynEdit is a change to obtext source, which replaces the bytes between Start and End with Text.
+type SynEdit struct {
+	// Start and End are the byte offsets of the replaced bytes in the source before the edit.
+	// For an insertion they are equal.
+	Start, End int
+	// Text is the text that replaces them.
+	Text string
+}
+
+// Apply returns a copy of the source with the edit applied.
+func (e SynEdit) Apply(src []byte) []byte {
+	res := make([]byte, 0, len(src)-(e.End-e.Start)+len(e.Text))
+	res = append(res, src[:e.Start]...)
+	res = append(res, e.Text...)
+	return append(res, src[e.End:]...)
+}
+
+// ReparseSynBytes parses src, which is the source that prev was parsed from with the edit applied, reusing as much of prev as possible.
+// This is much faster than ParseSynBytes for small edits to large documents, such as when typing in an editor.
+// Only the innermost arg containing the edit is parsed again. Nodes before it are shared with prev, and nodes after it are copied with their positions moved,
+// so prev is not modified and can still be used.
+// If the edit is not inside an arg, or it changes where that arg ends, the whole source is parsed again.
+// Either way, the result is the same as ParseSynBytes(src), including any error.
+// prev must have been returned by ParseSynBytes or ReparseSynBytes, as the positions of its nodes are used to find the edit.
+func ReparseSynBytes(prev *ObjectSynNode, src []byte, edit SynEdit) (*ObjectSynNode, error) {
+	if prev == nil {
+		return ParseSynBytes(src)
+	}
+	path := editPath(prev, edit)
+	if len(path) == 0 {
+		return ParseSynBytes(src)
+	}
+	last := path[len(path)-1]
+	old := last.obj.Args[last.arg]
+	r := &synReparser{
+		p:     newSynParser(src),
+		delta: len(edit.Text) - (edit.End - edit.Start),
+	}
+	if old.Pos.Offset >= r.p.end {
+		return ParseSynBytes(src)
+	}
+	// The new arg must end at the same place as the old one did, or the objects around it would be parsed differently
+	arg, remaining := r.p.tryParseArg(src[old.Pos.Offset:r.p.end])
+	if arg == nil || r.p.end-len(remaining) != old.End.Offset+r.delta {
+		return ParseSynBytes(src)
+	}
+	removeWhitespaceOnlyTextFromChildren(arg)
+	stripWhitespaceFromEndChildren(arg)
+	cleanupEscapedSpecialChars(arg)
+	return r.rebuild(prev, path, arg), nil
+}
+
+// editStep is an object and the index of its arg that contains an edit.
+type editStep struct {
+	obj *ObjectSynNode
+	arg int
+}
+
+// editPath returns the path from the root to the innermost arg that contains the whole edit, not including its braces.
+// It returns nil if the edit is not inside any arg.
+func editPath(root *ObjectSynNode, edit SynEdit) []editStep {
+	var path []editStep
+	for obj := root; obj != nil; {
+		i := containingArg(obj, edit)
+		if i < 0 {
+			break
+		}
+		path = append(path, editStep{obj: obj, arg: i})
+		// Only the last object that starts before the edit can contain it
+		var next *ObjectSynNode
+		for _, e := range obj.Args[i].Elements {
+			if child, ok := e.(*ObjectSynNode); ok && child.Pos.Offset < edit.Start {
+				next = child
+			}
+		}
+		obj = next
+	}
+	return path
+}
+
+// containingArg returns the index of the arg of the object that contains the whole edit, not including its braces, or -1 if there is none.
+func containingArg(obj *ObjectSynNode, edit SynEdit) int {
+	for i, a := range obj.Args {
+		if a.End.IsValid() && a.Pos.Offset < edit.Start && edit.End < a.End.Offset {
+			return i
+		}
+	}
+	return -1
+}
+
+// synReparser builds the new syntax tree around a reparsed arg.
+type synReparser struct {
+	// p is the parser of the new source, which is used to find the new positions of nodes.
+	p *synParser
+	// delta is how many bytes longer the source is after the edit.
+	delta int
+}
+
+// rebuild returns a copy of the object along the path, with the innermost arg on the path replaced.
+// Everything before the replaced arg is shared, and everything after it is moved.
+func (r *synReparser) rebuild(obj *ObjectSynNode, path []editStep, replacement *ArgSynNode) *ObjectSynNode {
+	res := &ObjectSynNode{Type: obj.Type, Pos: obj.Pos, Args: make([]*ArgSynNode, len(obj.Args))}
+	step := path[0]
+	for i, a := range obj.Args {
+		switch {
+		case i < step.arg:
+			res.Args[i] = a
+		case i > step.arg:
+			res.Args[i] = r.moveArg(a)
+		case len(path) == 1:
+			res.Args[i] = replacement
+		default:
+			arg := &ArgSynNode{Pos: a.Pos, End: r.move(a.End), Elements: make([]SynElement, len(a.Elements))}
+			moving := false
+			for j, e := range a.Elements {
+				switch {
+				case e == SynElement(path[1].obj):
+					arg.Elements[j] = r.rebuild(path[1].obj, path[1:], replacement)
+					moving = true
+				case moving:
+					arg.Elements[j] = r.moveElement(e)
+				default:
+					arg.Elements[j] = e
+				}
+			}
+			res.Args[i] = arg
+		}
+	}
+	return res
+}
+
+// move returns the new position of a position after the edit.
+func (r *synReparser) move(pos Position) Position {
+	return r.p.posAt(pos.Offset + r.delta)
+}
+
+// moveArg returns a copy of an arg after the edit, with all positions moved.
+func (r *synReparser) moveArg(a *ArgSynNode) *ArgSynNode {
+	res := &ArgSynNode{Pos: r.move(a.Pos), End: r.move(a.End), Elements: make([]SynElement, len(a.Elements))}
+	for i, e := range a.Elements {
+		res.Elements[i] = r.moveElement(e)
+	}
+	return res
+}
+
+// moveElement returns a copy of an element after the edit, with all positions moved.
+func (r *synReparser) moveElement(e SynElement) SynElement {
+	switch e := e.(type) {
+	case *ObjectSynNode:
+		res := &ObjectSynNode{Type: e.Type, Pos: r.move(e.Pos), Args: make([]*ArgSynNode, len(e.Args))}
+		for i, a := range e.Args {
+			res.Args[i] = r.moveArg(a)
+		}
+		return res
+	case *TextSynNode:
+		return &TextSynNode{Value: e.Value, Pos: r.move(e.Pos)}
+	}
+	panic("unknown type")
+}
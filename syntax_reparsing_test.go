@@ -0,0 +1,169 @@
+package obtext
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const reparseSource = "@doc{\n\t@para{Some @bold{bold} text}\n\t@para{A @link{https://example.com}{link} and \\@escaped}\n\t@list{a}{b @bold{c}}\n}"
+
+// assertReparse checks that reparsing prev after the edit gives the same result as parsing the edited source from scratch, and that prev is not modified.
+func assertReparse(t *testing.T, src []byte, edit SynEdit) {
+	t.Helper()
+	prev, err := ParseSynBytes(src)
+	if err != nil {
+		t.Fatalf("%q does not parse: %v", src, err)
+	}
+	before, _ := ParseSynBytes(src)
+	newSrc := edit.Apply(src)
+	got, gotErr := ReparseSynBytes(prev, newSrc, edit)
+	expected, expectedErr := ParseSynBytes(newSrc)
+	if (gotErr == nil) != (expectedErr == nil) || (gotErr != nil && gotErr.Error() != expectedErr.Error()) {
+		t.Errorf("%+v on %q: got error %v, want %v", edit, src, gotErr, expectedErr)
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Errorf("%+v on %q: reparsed tree differs from parsing %q:\n%s\n%s", edit, src, newSrc, FormatSyn(got), FormatSyn(expected))
+	}
+	if !reflect.DeepEqual(prev, before) {
+		t.Errorf("%+v on %q: the previous tree was modified", edit, src)
+	}
+}
+
+// editAt returns an edit that replaces the first occurrence of old in src with text.
+func editAt(t *testing.T, src, old, text string) SynEdit {
+	t.Helper()
+	i := strings.Index(src, old)
+	if i < 0 {
+		t.Fatalf("%q is not in the source", old)
+	}
+	return SynEdit{Start: i, End: i + len(old), Text: text}
+}
+
+func TestSynEditApply(t *testing.T) {
+	src := []byte("hello world")
+	if got := string(SynEdit{Start: 6, End: 11, Text: "there"}.Apply(src)); got != "hello there" {
+		t.Errorf("got %q", got)
+	}
+	if got := string(SynEdit{Start: 5, End: 5, Text: ","}.Apply(src)); got != "hello, world" {
+		t.Errorf("got %q", got)
+	}
+	if string(src) != "hello world" {
+		t.Errorf("the source was modified")
+	}
+}
+
+func TestReparseSyn(t *testing.T) {
+	src := reparseSource
+	end := len(src)
+	cases := []struct {
+		name string
+		edit SynEdit
+	}{
+		// Inside args
+		{"replace text", editAt(t, src, "Some", "Other")},
+		{"insert text", editAt(t, src, "text}", "more text}")},
+		{"delete text", editAt(t, src, "Some ", "")},
+		{"insert newline", editAt(t, src, "Some", "Some\n\n")},
+		{"add object", editAt(t, src, "text}", "text @bold{x}}")},
+		{"remove object", editAt(t, src, "@bold{bold}", "")},
+		{"rename object", editAt(t, src, "@bold{c}", "@em{c}")},
+		{"add escape", editAt(t, src, "and", `\{and\}`)},
+		{"break escape", editAt(t, src, `\@escaped`, `@escaped`)},
+		{"whitespace only", editAt(t, src, "{a}", "{  }")},
+		{"empty arg", editAt(t, src, "{a}", "{}")},
+		// Across arg boundaries
+		{"merge args", editAt(t, src, "a}{b", "ab")},
+		{"split arg", editAt(t, src, "Some @bold", "Some}{@bold")},
+		{"across objects", editAt(t, src, "text}\n\t@para{A", "text A")},
+		{"add arg", editAt(t, src, "{link}", "{link}{extra}")},
+		// Adding or removing braces
+		{"add open brace", editAt(t, src, "Some", "So{me")},
+		{"add close brace", editAt(t, src, "Some", "So}me")},
+		{"remove open brace", editAt(t, src, "@bold{bold}", "@boldbold}")},
+		{"remove close brace", editAt(t, src, "{bold}", "{bold")},
+		{"add balanced braces", editAt(t, src, "Some", "@x{So}{me}")},
+		// At the start and end of the source
+		{"append at EOF", SynEdit{Start: end, End: end, Text: "\n"}},
+		{"append text at EOF", SynEdit{Start: end, End: end, Text: "x"}},
+		{"delete at EOF", SynEdit{Start: end - 1, End: end}},
+		{"insert before EOF", SynEdit{Start: end - 1, End: end - 1, Text: "@para{end}"}},
+		{"insert at start", SynEdit{Start: 0, End: 0, Text: "  "}},
+		{"rename root", SynEdit{Start: 1, End: 4, Text: "document"}},
+		{"replace everything", SynEdit{Start: 0, End: end, Text: "@x{y}"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assertReparse(t, []byte(src), c.edit)
+		})
+	}
+}
+
+func TestReparseSynSharesNodes(t *testing.T) {
+	src := []byte(reparseSource)
+	prev, err := ParseSynBytes(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit := editAt(t, reparseSource, "and", "or")
+	got, err := ReparseSynBytes(prev, edit.Apply(src), edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first paragraph is before the edit so it is shared, but the list is after it so it is moved
+	if got.Args[0].Elements[0] != prev.Args[0].Elements[0] {
+		t.Errorf("the object before the edit was not shared with the previous tree")
+	}
+	if got.Args[0].Elements[2] == prev.Args[0].Elements[2] {
+		t.Errorf("the object after the edit was shared, but its position has changed")
+	}
+}
+
+func TestReparseSynNil(t *testing.T) {
+	src := []byte("@a{b}")
+	got, err := ReparseSynBytes(nil, src, SynEdit{})
+	expected, _ := ParseSynBytes(src)
+	if err != nil || !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, %v", got, err)
+	}
+}
+
+func TestReparseSynRandom(t *testing.T) {
+	// Random small edits, applied one after another to the same document as if typing in an editor
+	pieces := []string{"@", "@b", "@para", "{", "}", "{x}", `\`, `\@`, `\}`, " ", "\n", "\t", "x", "text ", "."}
+	rng := rand.New(rand.NewSource(1))
+	reparsed := 0
+	for doc := 0; doc < 200; doc++ {
+		src := []byte(reparseSource)
+		prev, _ := ParseSynBytes(src)
+		for i := 0; i < 50; i++ {
+			start := rng.Intn(len(src) + 1)
+			end := min(start+rng.Intn(4), len(src))
+			if rng.Intn(3) == 0 {
+				end = start
+			}
+			text := ""
+			for j := rng.Intn(3); j > 0; j-- {
+				text += pieces[rng.Intn(len(pieces))]
+			}
+			edit := SynEdit{Start: start, End: end, Text: text}
+			newSrc := edit.Apply(src)
+			got, gotErr := ReparseSynBytes(prev, newSrc, edit)
+			expected, expectedErr := ParseSynBytes(newSrc)
+			if (gotErr == nil) != (expectedErr == nil) || (gotErr != nil && gotErr.Error() != expectedErr.Error()) {
+				t.Fatalf("%+v on %q: got error %v, want %v", edit, src, gotErr, expectedErr)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Fatalf("%+v on %q: reparsed tree differs from parsing %q:\n%s\n%s", edit, src, newSrc, FormatSyn(got), FormatSyn(expected))
+			}
+			// Edits that break the document are undone, so that the next edit has a tree to reuse
+			if gotErr == nil {
+				src, prev = newSrc, got
+				reparsed++
+			}
+		}
+	}
+	if reparsed < 2000 {
+		t.Errorf("only %d random edits gave valid documents, so the test is not checking much", reparsed)
+	}
+}